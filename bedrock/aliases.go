@@ -0,0 +1,74 @@
+package bedrock
+
+import (
+	"slices"
+	"strings"
+)
+
+// defaultAlias maps an OpenAI model name onto a Bedrock model. Models that
+// are only served through cross-region inference profiles get the profile
+// of the region's geography.
+type defaultAlias struct {
+	modelID string
+	profile bool
+}
+
+var defaultAliases = map[string]defaultAlias{
+	"gpt-4.1":                {"anthropic.claude-sonnet-4-20250514-v1:0", true},
+	"gpt-4.1-mini":           {"amazon.nova-lite-v1:0", true},
+	"gpt-4.1-nano":           {"amazon.nova-micro-v1:0", true},
+	"gpt-4o":                 {"anthropic.claude-3-7-sonnet-20250219-v1:0", true},
+	"gpt-4o-mini":            {"amazon.nova-lite-v1:0", true},
+	"gpt-4-turbo":            {"anthropic.claude-3-7-sonnet-20250219-v1:0", true},
+	"gpt-3.5-turbo":          {"amazon.nova-lite-v1:0", true},
+	"gpt-3.5-turbo-instruct": {"amazon.nova-lite-v1:0", true},
+	"o1":                     {"anthropic.claude-sonnet-4-20250514-v1:0", true},
+	"o3":                     {"anthropic.claude-sonnet-4-20250514-v1:0", true},
+	"o1-mini":                {"amazon.nova-pro-v1:0", true},
+	"o3-mini":                {"amazon.nova-pro-v1:0", true},
+	"o4-mini":                {"amazon.nova-pro-v1:0", true},
+	"text-embedding-3-small": {"amazon.titan-embed-text-v2:0", false},
+	"text-embedding-3-large": {"cohere.embed-english-v3", false},
+	"text-embedding-ada-002": {"amazon.titan-embed-text-v2:0", false},
+}
+
+// govCloudUnavailable are the model families without inference profiles in
+// GovCloud. Their aliases are left out there, rather than naming profiles
+// that do not exist.
+var govCloudUnavailable = []string{"amazon.nova-"}
+
+// DefaultAliases returns the built-in aliases for region.
+func DefaultAliases(region string) map[string]ModelConfig {
+	geography := regionGeography(region)
+	aliases := map[string]ModelConfig{}
+	for name, alias := range defaultAliases {
+		modelID := alias.modelID
+		if geography == "us-gov" && slices.ContainsFunc(govCloudUnavailable, func(prefix string) bool {
+			return strings.HasPrefix(modelID, prefix)
+		}) {
+			continue
+		}
+		if alias.profile && geography != "" {
+			modelID = geography + "." + modelID
+		}
+		aliases[name] = ModelConfig{ModelID: modelID}
+	}
+	return aliases
+}
+
+// regionGeography returns the inference profile prefix for region, or ""
+// for regions outside the geographies Bedrock has profiles for.
+func regionGeography(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return "us-gov"
+	case strings.HasPrefix(region, "us-"):
+		return "us"
+	case strings.HasPrefix(region, "eu-"):
+		return "eu"
+	case strings.HasPrefix(region, "ap-"):
+		return "apac"
+	default:
+		return ""
+	}
+}
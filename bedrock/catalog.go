@@ -0,0 +1,209 @@
+package bedrock
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrock"
+	"github.com/aws/aws-sdk-go-v2/service/bedrock/types"
+)
+
+// CatalogLister is the part of the Bedrock control-plane API used to list
+// the models that can be invoked.
+type CatalogLister interface {
+	ListFoundationModels(
+		ctx context.Context,
+		params *bedrock.ListFoundationModelsInput,
+		optFns ...func(*bedrock.Options),
+	) (*bedrock.ListFoundationModelsOutput, error)
+	ListInferenceProfiles(
+		ctx context.Context,
+		params *bedrock.ListInferenceProfilesInput,
+		optFns ...func(*bedrock.Options),
+	) (*bedrock.ListInferenceProfilesOutput, error)
+}
+
+type CatalogModel struct {
+	ID       string
+	Provider string
+	Created  time.Time
+}
+
+// Catalog lists the chat models available in the account, caching the
+// result for ttl.
+type Catalog struct {
+	lister CatalogLister
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	models  []CatalogModel
+	fetched time.Time
+}
+
+func NewCatalog(lister CatalogLister, ttl time.Duration) *Catalog {
+	return &Catalog{lister: lister, ttl: ttl, now: time.Now}
+}
+
+func NewCatalogController(ttl time.Duration) (*Catalog, error) {
+	lister, err := NewCatalogLister()
+	if err != nil {
+		return nil, err
+	}
+	return NewCatalog(lister, ttl), nil
+}
+
+// NewCatalogLister returns a Bedrock control-plane client.
+func NewCatalogLister() (CatalogLister, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SDK config: %w", err)
+	}
+	return bedrock.NewFromConfig(cfg), nil
+}
+
+// Models returns the cached catalog, refreshing it once it is older than
+// the TTL. If a refresh fails the previous catalog is kept for another TTL.
+func (c *Catalog) Models(ctx context.Context) ([]CatalogModel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.models != nil && c.now().Sub(c.fetched) < c.ttl {
+		return c.models, nil
+	}
+
+	models, err := c.fetch(ctx)
+	if err != nil {
+		if c.models != nil {
+			slog.Warn("Failed to refresh Bedrock model catalog", "error", err)
+			c.fetched = c.now()
+			return c.models, nil
+		}
+		return nil, err
+	}
+	c.models = models
+	c.fetched = c.now()
+	return models, nil
+}
+
+// Model looks up id in the catalog.
+func (c *Catalog) Model(ctx context.Context, id string) (CatalogModel, bool, error) {
+	models, err := c.Models(ctx)
+	if err != nil {
+		return CatalogModel{}, false, err
+	}
+	for _, model := range models {
+		if model.ID == id {
+			return model, true, nil
+		}
+	}
+	return CatalogModel{}, false, nil
+}
+
+func (c *Catalog) fetch(ctx context.Context) ([]CatalogModel, error) {
+	foundationModels, err := c.lister.ListFoundationModels(ctx, &bedrock.ListFoundationModelsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to list foundation models", err)
+	}
+
+	var models []CatalogModel
+	chatModels := map[string]bool{}
+	for _, summary := range foundationModels.ModelSummaries {
+		if !isChatModel(summary) {
+			continue
+		}
+		modelID := aws.ToString(summary.ModelId)
+		chatModels[modelID] = true
+		// Some models can only be invoked through an inference profile.
+		if slices.Contains(summary.InferenceTypesSupported, types.InferenceTypeOnDemand) {
+			models = append(models, CatalogModel{
+				ID:       modelID,
+				Provider: strings.ToLower(aws.ToString(summary.ProviderName)),
+			})
+		}
+	}
+
+	input := &bedrock.ListInferenceProfilesInput{}
+	for {
+		profiles, err := c.lister.ListInferenceProfiles(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to list inference profiles", err)
+		}
+		for _, profile := range profiles.InferenceProfileSummaries {
+			if profile.Status != types.InferenceProfileStatusActive || !servesChatModels(profile, chatModels) {
+				continue
+			}
+			profileID := aws.ToString(profile.InferenceProfileId)
+			models = append(models, CatalogModel{
+				ID:       profileID,
+				Provider: ModelProvider(profileID),
+				Created:  aws.ToTime(profile.CreatedAt),
+			})
+		}
+		if profiles.NextToken == nil {
+			break
+		}
+		input.NextToken = profiles.NextToken
+	}
+
+	slices.SortFunc(models, func(a, b CatalogModel) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return models, nil
+}
+
+// isChatModel reports whether a foundation model takes and returns text
+// through the Converse API. Embedding and image generation models, legacy
+// text models without Converse support, and retired models are left out.
+func isChatModel(summary types.FoundationModelSummary) bool {
+	if summary.ModelLifecycle != nil && summary.ModelLifecycle.Status != types.FoundationModelLifecycleStatusActive {
+		return false
+	}
+	if !supportsConverse(aws.ToString(summary.ModelId)) {
+		return false
+	}
+	return slices.Contains(summary.InputModalities, types.ModelModalityText) &&
+		slices.Contains(summary.OutputModalities, types.ModelModalityText)
+}
+
+func servesChatModels(profile types.InferenceProfileSummary, chatModels map[string]bool) bool {
+	if len(profile.Models) == 0 {
+		return false
+	}
+	for _, model := range profile.Models {
+		// Model ARNs end in foundation-model/<model id>.
+		arn := aws.ToString(model.ModelArn)
+		if !chatModels[arn[strings.LastIndex(arn, "/")+1:]] {
+			return false
+		}
+	}
+	return true
+}
+
+// ModelProvider returns the provider of a Bedrock model ID, skipping the
+// geography prefix of an inference profile such as "us.".
+func ModelProvider(modelID string) string {
+	parts := strings.Split(modelID, ".")
+	if isProfileGeography(parts[0]) && len(parts) > 2 {
+		parts = parts[1:]
+	}
+	return parts[0]
+}
+
+// isProfileGeography reports whether prefix is the geography of a
+// cross-region inference profile ID.
+func isProfileGeography(prefix string) bool {
+	switch prefix {
+	case "us", "eu", "apac", "us-gov", "global":
+		return true
+	default:
+		return false
+	}
+}
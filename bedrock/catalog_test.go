@@ -0,0 +1,147 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrock"
+	"github.com/aws/aws-sdk-go-v2/service/bedrock/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCatalogLister struct {
+	models   []types.FoundationModelSummary
+	profiles [][]types.InferenceProfileSummary
+	err      error
+	calls    int
+}
+
+func (m *mockCatalogLister) ListFoundationModels(
+	context.Context,
+	*bedrock.ListFoundationModelsInput,
+	...func(*bedrock.Options),
+) (*bedrock.ListFoundationModelsOutput, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &bedrock.ListFoundationModelsOutput{ModelSummaries: m.models}, nil
+}
+
+func (m *mockCatalogLister) ListInferenceProfiles(
+	_ context.Context,
+	params *bedrock.ListInferenceProfilesInput,
+	_ ...func(*bedrock.Options),
+) (*bedrock.ListInferenceProfilesOutput, error) {
+	page := 0
+	if params.NextToken != nil {
+		page = 1
+	}
+	output := &bedrock.ListInferenceProfilesOutput{InferenceProfileSummaries: m.profiles[page]}
+	if page+1 < len(m.profiles) {
+		output.NextToken = aws.String("next")
+	}
+	return output, nil
+}
+
+func foundationModel(id string, input, output []types.ModelModality, inference ...types.InferenceType) types.FoundationModelSummary {
+	return types.FoundationModelSummary{
+		ModelId:                 aws.String(id),
+		ProviderName:            aws.String("Anthropic"),
+		InputModalities:         input,
+		OutputModalities:        output,
+		InferenceTypesSupported: inference,
+		ModelLifecycle:          &types.FoundationModelLifecycle{Status: types.FoundationModelLifecycleStatusActive},
+	}
+}
+
+func inferenceProfile(id string, modelIDs ...string) types.InferenceProfileSummary {
+	profile := types.InferenceProfileSummary{
+		InferenceProfileId: aws.String(id),
+		Status:             types.InferenceProfileStatusActive,
+	}
+	for _, modelID := range modelIDs {
+		profile.Models = append(profile.Models, types.InferenceProfileModel{
+			ModelArn: aws.String("arn:aws:bedrock:us-east-1::foundation-model/" + modelID),
+		})
+	}
+	return profile
+}
+
+func TestCatalogModels(t *testing.T) {
+	text := []types.ModelModality{types.ModelModalityText}
+	textAndImage := []types.ModelModality{types.ModelModalityText, types.ModelModalityImage}
+
+	retired := foundationModel("anthropic.claude-v2", text, text, types.InferenceTypeOnDemand)
+	retired.ModelLifecycle.Status = types.FoundationModelLifecycleStatusLegacy
+
+	lister := &mockCatalogLister{
+		models: []types.FoundationModelSummary{
+			foundationModel("anthropic.claude-3-haiku", textAndImage, text, types.InferenceTypeOnDemand),
+			foundationModel("anthropic.claude-3-7-sonnet", textAndImage, text, types.InferenceType("INFERENCE_PROFILE")),
+			foundationModel("amazon.titan-embed-text-v2:0", text, []types.ModelModality{types.ModelModalityEmbedding}, types.InferenceTypeOnDemand),
+			foundationModel("stability.sd3-large-v1:0", text, []types.ModelModality{types.ModelModalityImage}, types.InferenceTypeOnDemand),
+			foundationModel("amazon.titan-text-express-v1", text, text, types.InferenceTypeOnDemand),
+			foundationModel("cohere.command-text-v14", text, text, types.InferenceTypeOnDemand),
+			retired,
+		},
+		profiles: [][]types.InferenceProfileSummary{
+			{inferenceProfile("us.anthropic.claude-3-7-sonnet", "anthropic.claude-3-7-sonnet")},
+			{inferenceProfile("us.stability.sd3-large-v1:0", "stability.sd3-large-v1:0")},
+		},
+	}
+
+	catalog := NewCatalog(lister, time.Hour)
+	models, err := catalog.Models(context.Background())
+	require.NoError(t, err)
+
+	var ids []string
+	for _, model := range models {
+		ids = append(ids, model.ID)
+	}
+	assert.Equal(t, []string{"anthropic.claude-3-haiku", "us.anthropic.claude-3-7-sonnet"}, ids)
+	assert.Equal(t, "anthropic", models[0].Provider)
+	assert.Equal(t, "anthropic", models[1].Provider)
+
+	model, ok, err := catalog.Model(context.Background(), "us.anthropic.claude-3-7-sonnet")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "us.anthropic.claude-3-7-sonnet", model.ID)
+}
+
+func TestCatalogCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	text := []types.ModelModality{types.ModelModalityText}
+	lister := &mockCatalogLister{
+		models:   []types.FoundationModelSummary{foundationModel("anthropic.claude-3-haiku", text, text, types.InferenceTypeOnDemand)},
+		profiles: [][]types.InferenceProfileSummary{{}},
+	}
+	catalog := NewCatalog(lister, time.Minute)
+	catalog.now = func() time.Time { return now }
+
+	_, err := catalog.Models(context.Background())
+	require.NoError(t, err)
+	_, err = catalog.Models(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, lister.calls)
+
+	now = now.Add(2 * time.Minute)
+	lister.err = errors.New("throttled")
+	models, err := catalog.Models(context.Background())
+	require.NoError(t, err, "a failed refresh keeps the previous catalog")
+	assert.Len(t, models, 1)
+	assert.Equal(t, 2, lister.calls)
+
+	_, err = NewCatalog(lister, time.Minute).Models(context.Background())
+	assert.Error(t, err)
+}
+
+func TestModelProvider(t *testing.T) {
+	assert.Equal(t, "anthropic", ModelProvider("anthropic.claude-3-haiku-20240307-v1:0"))
+	assert.Equal(t, "meta", ModelProvider("us.meta.llama3-2-3b-instruct-v1:0"))
+	assert.Equal(t, "claude", ModelProvider("claude"))
+}
@@ -3,9 +3,12 @@ package bedrock
 import (
 	"context"
 	"fmt"
+	"strconv"
 
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 )
 
 type BedrockConverser interface {
@@ -21,8 +24,35 @@ type BedrockConverser interface {
 	) (*bedrockruntime.ConverseStreamOutput, error)
 }
 
+// EventStream is the reader side of a ConverseStream response.
+type EventStream = bedrockruntime.ConverseStreamOutputReader
+
+type Converser interface {
+	Converse(
+		ctx context.Context,
+		params *bedrockruntime.ConverseInput,
+		optFns ...func(*bedrockruntime.Options),
+	) (*bedrockruntime.ConverseOutput, error)
+	ConverseStream(
+		ctx context.Context,
+		params *bedrockruntime.ConverseStreamInput,
+		optFns ...func(*bedrockruntime.Options),
+	) (EventStream, error)
+}
+
+// Embedder invokes embedding models, which have no Converse API.
+type Embedder interface {
+	InvokeModel(
+		ctx context.Context,
+		params *bedrockruntime.InvokeModelInput,
+		optFns ...func(*bedrockruntime.Options),
+	) (*bedrockruntime.InvokeModelOutput, error)
+}
+
 type Client struct {
-	client BedrockConverser
+	client  BedrockConverser
+	invoker Embedder
+	region  string
 }
 
 func NewController() (Client, error) {
@@ -34,10 +64,17 @@ func NewController() (Client, error) {
 	client := bedrockruntime.NewFromConfig(cfg)
 
 	return Client{
-		client: client,
+		client:  client,
+		invoker: client,
+		region:  cfg.Region,
 	}, nil
 }
 
+// Region returns the AWS region the client sends requests to.
+func (c Client) Region() string {
+	return c.region
+}
+
 func (c Client) Converse(
 	ctx context.Context,
 	bedrockReq *bedrockruntime.ConverseInput,
@@ -54,10 +91,36 @@ func (c Client) ConverseStream(
 	ctx context.Context,
 	bedrockReq *bedrockruntime.ConverseStreamInput,
 	optFns ...func(*bedrockruntime.Options),
-) (*bedrockruntime.ConverseStreamOutput, error) {
+) (EventStream, error) {
 	output, err := c.client.ConverseStream(ctx, bedrockReq, optFns...)
 	if err != nil {
 		return nil, fmt.Errorf("%w: failed to invoke bedrock", err)
 	}
+	return output.GetStream(), nil
+}
+
+func (c Client) InvokeModel(
+	ctx context.Context,
+	bedrockReq *bedrockruntime.InvokeModelInput,
+	optFns ...func(*bedrockruntime.Options),
+) (*bedrockruntime.InvokeModelOutput, error) {
+	output, err := c.invoker.InvokeModel(ctx, bedrockReq, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to invoke bedrock", err)
+	}
 	return output, nil
 }
+
+// InputTokenCount returns the input token count Bedrock reports in the
+// response headers of an InvokeModel call, or 0 if it is missing.
+func InputTokenCount(output *bedrockruntime.InvokeModelOutput) int {
+	resp, ok := awsmiddleware.GetRawResponse(output.ResultMetadata).(*smithyhttp.Response)
+	if !ok {
+		return 0
+	}
+	count, err := strconv.Atoi(resp.Header.Get("X-Amzn-Bedrock-Input-Token-Count"))
+	if err != nil {
+		return 0
+	}
+	return count
+}
@@ -0,0 +1,85 @@
+package bedrock
+
+// InferenceParams adjusts the inference parameters of a model's requests,
+// so that clients need not know what each model accepts.
+type InferenceParams struct {
+	MaxTokens   *Param[int]     `json:"max_tokens,omitempty" yaml:"max_tokens"`
+	Temperature *Param[float64] `json:"temperature,omitempty" yaml:"temperature"`
+	TopP        *Param[float64] `json:"top_p,omitempty" yaml:"top_p"`
+	Stop        *StopParam      `json:"stop,omitempty" yaml:"stop"`
+	// Strip lists request fields that are never sent to the model, such as
+	// top_p for models that reject it alongside temperature.
+	Strip []string `json:"strip,omitempty" yaml:"strip"`
+}
+
+// Param fills in a parameter the request left out with Default, clamps it
+// to Min and Max, or replaces it with Force.
+type Param[T int | float64] struct {
+	Default *T `json:"default,omitempty" yaml:"default"`
+	Min     *T `json:"min,omitempty" yaml:"min"`
+	Max     *T `json:"max,omitempty" yaml:"max"`
+	Force   *T `json:"force,omitempty" yaml:"force"`
+}
+
+// Apply returns the value to send for a requested value, which is nil if
+// the request has none.
+func (p *Param[T]) Apply(value *T) *T {
+	if p == nil {
+		return value
+	}
+	if p.Force != nil {
+		result := *p.Force
+		return &result
+	}
+	if value == nil {
+		value = p.Default
+	}
+	if value == nil {
+		return nil
+	}
+	result := *value
+	if p.Min != nil && result < *p.Min {
+		result = *p.Min
+	}
+	if p.Max != nil && result > *p.Max {
+		result = *p.Max
+	}
+	return &result
+}
+
+// StopParam is Param for stop sequences. Max limits how many are sent, as
+// some models accept only a few.
+type StopParam struct {
+	Default []string `json:"default,omitempty" yaml:"default"`
+	Max     int      `json:"max,omitempty" yaml:"max"`
+	Force   []string `json:"force,omitempty" yaml:"force"`
+}
+
+func (p *StopParam) Apply(stop []string) []string {
+	if p == nil {
+		return stop
+	}
+	if p.Force != nil {
+		return p.Force
+	}
+	if len(stop) == 0 {
+		stop = p.Default
+	}
+	if p.Max > 0 && len(stop) > p.Max {
+		stop = stop[:p.Max]
+	}
+	return stop
+}
+
+// Strips reports whether field must not be sent to the model.
+func (p *InferenceParams) Strips(field string) bool {
+	if p == nil {
+		return false
+	}
+	for _, stripped := range p.Strip {
+		if stripped == field {
+			return true
+		}
+	}
+	return false
+}
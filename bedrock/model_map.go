@@ -1,29 +1,373 @@
 package bedrock
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
-type ModelMap map[string]string
+var ErrModelNotFound = errors.New("model not found")
+
+// ModelMap maps OpenAI model names onto Bedrock. Names are resolved from
+// the user's aliases first, then the rules in order, then the built-in
+// aliases and finally the Default. Anything else is passed to Bedrock
+// unchanged, unless Strict. The resulting model IDs are then given the
+// inference profile the Profiles call for.
+type ModelMap struct {
+	Aliases  map[string]ModelConfig
+	Rules    []ModelRule
+	Builtins map[string]ModelConfig
+	Default  *ModelConfig
+	Strict   bool
+	Profiles ProfileResolver
+}
+
+// ModelConfig describes how an OpenAI model name maps onto Bedrock. In
+// MODEL_NAME_MAP it is either a plain Bedrock model ID or an object.
+type ModelConfig struct {
+	ModelID string `json:"model" yaml:"model"`
+	// Passthrough lists the request fields that may be forwarded to the
+	// model as additionalModelRequestFields. "*" allows any field.
+	Passthrough []string `json:"passthrough,omitempty" yaml:"passthrough"`
+	// Guardrail, if set, is applied to every request for the model.
+	Guardrail *GuardrailConfig `json:"guardrail,omitempty" yaml:"guardrail"`
+	// Inference, if set, adjusts the inference parameters of its requests.
+	Inference *InferenceParams `json:"inference,omitempty" yaml:"inference"`
+}
+
+type GuardrailConfig struct {
+	ID      string `json:"id" yaml:"id"`
+	Version string `json:"version" yaml:"version"`
+}
+
+func (c *ModelConfig) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		*c = ModelConfig{}
+		return json.Unmarshal(data, &c.ModelID)
+	}
+
+	type alias ModelConfig
+	return json.Unmarshal(data, (*alias)(c))
+}
+
+func (c *ModelConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*c = ModelConfig{}
+		return node.Decode(&c.ModelID)
+	}
+	if err := checkYAMLFields(node, reflect.TypeOf(*c)); err != nil {
+		return err
+	}
+
+	type alias ModelConfig
+	return node.Decode((*alias)(c))
+}
+
+// ModelRule maps the names that match a glob or a regular expression. The
+// target model ID may refer to capture groups as $1 or ${1}; the wildcards
+// of a glob are numbered like groups.
+type ModelRule struct {
+	Match  string      `yaml:"match"`
+	Regex  string      `yaml:"regex"`
+	Config ModelConfig `yaml:",inline"`
+
+	pattern *regexp.Regexp
+}
+
+// NewModelRule returns a rule for the names matching either the glob match,
+// where * matches any run of characters and ? any one, or the regex, which
+// must match the whole name.
+func NewModelRule(match, regex string, config ModelConfig) (ModelRule, error) {
+	rule := ModelRule{Match: match, Regex: regex, Config: config}
+	switch {
+	case match != "" && regex != "":
+		return ModelRule{}, fmt.Errorf("rule %q has both match and regex", match)
+	case match != "":
+		rule.pattern = regexp.MustCompile(globToRegex(match))
+	case regex != "":
+		pattern, err := regexp.Compile("^(?:" + regex + ")$")
+		if err != nil {
+			return ModelRule{}, fmt.Errorf("%w: invalid regex in rule %q", err, regex)
+		}
+		rule.pattern = pattern
+	default:
+		return ModelRule{}, errors.New("rule has neither match nor regex")
+	}
+	return rule, nil
+}
+
+func globToRegex(glob string) string {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			pattern.WriteString("(.*)")
+		case '?':
+			pattern.WriteString("(.)")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	pattern.WriteString("$")
+	return pattern.String()
+}
 
-func NewModelMap() (ModelMap, error) {
-	envVarName := "MODEL_NAME_MAP"
-	modelNameMap := map[string]string{}
-	// read MODEL_NAME_MAP as json from env
-	err := json.Unmarshal([]byte(os.Getenv(envVarName)), &modelNameMap)
+func (r *ModelRule) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		Match string `json:"match"`
+		Regex string `json:"regex"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var config ModelConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+	rule, err := NewModelRule(fields.Match, fields.Regex, config)
 	if err != nil {
-		return nil, fmt.Errorf("%w: unable to unmarshal %s", err, envVarName)
+		return err
 	}
+	*r = rule
+	return nil
+}
 
-	return modelNameMap, nil
+func (r *ModelRule) UnmarshalYAML(node *yaml.Node) error {
+	if err := checkYAMLFields(node, reflect.TypeOf(*r)); err != nil {
+		return err
+	}
+	var fields struct {
+		Match string `yaml:"match"`
+		Regex string `yaml:"regex"`
+	}
+	if err := node.Decode(&fields); err != nil {
+		return err
+	}
+	type alias ModelConfig
+	var config ModelConfig
+	if err := node.Decode((*alias)(&config)); err != nil {
+		return err
+	}
+	rule, err := NewModelRule(fields.Match, fields.Regex, config)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*r = rule
+	return nil
 }
 
-func (m ModelMap) BedrockModelID(openAIModel string) string {
-	mappedModelID, ok := m[openAIModel]
+// resolve returns the configuration for name if the rule matches it.
+func (r ModelRule) resolve(name string) (ModelConfig, bool) {
+	match := r.pattern.FindStringSubmatchIndex(name)
+	if match == nil {
+		return ModelConfig{}, false
+	}
+	config := r.Config
+	if config.ModelID != "" {
+		config.ModelID = string(r.pattern.ExpandString(nil, config.ModelID, name, match))
+	}
+	return config, true
+}
+
+// ModelSettings are the user's model mappings, from the config file and
+// the environment.
+type ModelSettings struct {
+	Aliases           map[string]ModelConfig `yaml:"aliases"`
+	Rules             []ModelRule            `yaml:"rules"`
+	Default           *ModelConfig           `yaml:"default"`
+	Strict            bool                   `yaml:"strict"`
+	InferenceProfiles string                 `yaml:"inference_profiles"`
+}
+
+// ApplyEnv overrides the settings with the environment. MODEL_NAME_MAP
+// entries replace aliases of the same name, MODEL_NAME_RULES are tried
+// before the other rules, and MODEL_NAME_DEFAULT, MODEL_NAME_STRICT and
+// INFERENCE_PROFILES replace their settings.
+func (s *ModelSettings) ApplyEnv() error {
+	if value := strings.TrimSpace(os.Getenv("MODEL_NAME_MAP")); value != "" {
+		var aliases map[string]ModelConfig
+		if err := json.Unmarshal([]byte(value), &aliases); err != nil {
+			return fmt.Errorf("%w: unable to unmarshal MODEL_NAME_MAP", err)
+		}
+		if s.Aliases == nil {
+			s.Aliases = map[string]ModelConfig{}
+		}
+		for name, config := range aliases {
+			s.Aliases[name] = config
+		}
+	}
+
+	if value := strings.TrimSpace(os.Getenv("MODEL_NAME_RULES")); value != "" {
+		var rules []ModelRule
+		if err := json.Unmarshal([]byte(value), &rules); err != nil {
+			return fmt.Errorf("%w: unable to unmarshal MODEL_NAME_RULES", err)
+		}
+		s.Rules = append(rules, s.Rules...)
+	}
+
+	if value := strings.TrimSpace(os.Getenv("MODEL_NAME_DEFAULT")); value != "" {
+		var config ModelConfig
+		if !strings.HasPrefix(value, "{") {
+			config.ModelID = value
+		} else if err := json.Unmarshal([]byte(value), &config); err != nil {
+			return fmt.Errorf("%w: unable to unmarshal MODEL_NAME_DEFAULT", err)
+		}
+		s.Default = &config
+	}
+
+	if value := strings.TrimSpace(os.Getenv("MODEL_NAME_STRICT")); value != "" {
+		strict, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%w: unable to parse MODEL_NAME_STRICT", err)
+		}
+		s.Strict = strict
+	}
+
+	if value := strings.TrimSpace(os.Getenv("INFERENCE_PROFILES")); value != "" {
+		s.InferenceProfiles = value
+	}
+	return nil
+}
+
+// ModelMap returns the built-in aliases for region, overridden and
+// extended by the settings. The inference profile mode is auto by default.
+func (s ModelSettings) ModelMap(region string) (ModelMap, error) {
+	profileMode, err := parseProfileMode(s.InferenceProfiles)
+	if err != nil {
+		return ModelMap{}, err
+	}
+
+	aliases := map[string]ModelConfig{}
+	for name, config := range s.Aliases {
+		aliases[name] = config
+	}
+	return ModelMap{
+		Aliases:  aliases,
+		Rules:    s.Rules,
+		Builtins: DefaultAliases(region),
+		Default:  s.Default,
+		Strict:   s.Strict,
+		Profiles: ProfileResolver{Mode: profileMode, Region: region},
+	}, nil
+}
+
+// NewModelMap returns the model map for region configured by the
+// environment alone.
+func NewModelMap(region string) (ModelMap, error) {
+	var settings ModelSettings
+	if err := settings.ApplyEnv(); err != nil {
+		return ModelMap{}, err
+	}
+	modelMap, err := settings.ModelMap(region)
+	if err != nil {
+		return ModelMap{}, fmt.Errorf("%w: unable to parse INFERENCE_PROFILES", err)
+	}
+	return modelMap, nil
+}
+
+// Resolve returns the configuration for openAIModel. Unmapped models are
+// passed through to Bedrock unchanged, or rejected with ErrModelNotFound if
+// the map is strict. Models that need an inference profile the region has
+// none for are rejected with ErrProfileRequired.
+func (m ModelMap) Resolve(openAIModel string) (ModelConfig, error) {
+	config, ok := m.lookup(openAIModel)
 	if !ok {
-		return openAIModel
+		if m.Strict {
+			return ModelConfig{}, fmt.Errorf("%w: %s", ErrModelNotFound, openAIModel)
+		}
+		config = ModelConfig{}
+	}
+	if config.ModelID == "" {
+		config.ModelID = openAIModel
+	}
+
+	modelID, err := m.Profiles.Resolve(config.ModelID)
+	if err != nil {
+		return ModelConfig{}, err
+	}
+	config.ModelID = modelID
+	return config, nil
+}
+
+func (m ModelMap) lookup(openAIModel string) (ModelConfig, bool) {
+	if config, ok := m.Aliases[openAIModel]; ok {
+		return config, true
+	}
+	for _, rule := range m.Rules {
+		if config, ok := rule.resolve(openAIModel); ok {
+			return config, true
+		}
+	}
+	if config, ok := m.Builtins[openAIModel]; ok {
+		return config, true
+	}
+	if m.Default != nil {
+		return *m.Default, true
+	}
+	return ModelConfig{}, false
+}
+
+// Config returns the configuration for openAIModel, passing unmapped models
+// through even if the map is strict.
+func (m ModelMap) Config(openAIModel string) ModelConfig {
+	config, err := m.Resolve(openAIModel)
+	if err != nil {
+		return ModelConfig{ModelID: openAIModel}
+	}
+	return config
+}
+
+// Names returns the aliased model names in order. Names that only rules
+// match are not listed.
+func (m ModelMap) Names() []string {
+	names := make([]string, 0, len(m.Aliases)+len(m.Builtins))
+	for name := range m.Aliases {
+		names = append(names, name)
+	}
+	for name := range m.Builtins {
+		if _, ok := m.Aliases[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m ModelMap) BedrockModelID(openAIModel string) string {
+	return m.Config(openAIModel).ModelID
+}
+
+// FilterPassthrough returns the fields of extra that the model allows to be
+// forwarded to Bedrock.
+func (c ModelConfig) FilterPassthrough(extra map[string]interface{}) (map[string]interface{}, []string) {
+	allowed := map[string]interface{}{}
+	var dropped []string
+	for key, value := range extra {
+		if c.allowsPassthrough(key) {
+			allowed[key] = value
+		} else {
+			dropped = append(dropped, key)
+		}
+	}
+	return allowed, dropped
+}
+
+func (c ModelConfig) allowsPassthrough(key string) bool {
+	for _, field := range c.Passthrough {
+		if field == "*" || field == key {
+			return true
+		}
 	}
-	return mappedModelID
+	return false
 }
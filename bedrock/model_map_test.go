@@ -0,0 +1,160 @@
+package bedrock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewModelMap(t *testing.T) {
+	t.Setenv("MODEL_NAME_MAP", "")
+	t.Setenv("MODEL_NAME_RULES", "")
+	t.Setenv("MODEL_NAME_DEFAULT", "")
+	modelMap, err := NewModelMap("eu-west-1")
+	require.NoError(t, err)
+	assert.Equal(t, "eu.anthropic.claude-3-7-sonnet-20250219-v1:0", modelMap.BedrockModelID("gpt-4o"))
+	assert.Equal(t, "amazon.titan-embed-text-v2:0", modelMap.BedrockModelID("text-embedding-3-small"))
+
+	t.Setenv("MODEL_NAME_MAP", `{"gpt-4o": "anthropic.claude-3-5-sonnet-20241022-v2:0", "mine": {"model": "meta.llama3-8b-instruct-v1:0"}}`)
+	modelMap, err = NewModelMap("us-east-1")
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic.claude-3-5-sonnet-20241022-v2:0", modelMap.BedrockModelID("gpt-4o"))
+	assert.Equal(t, "us.amazon.nova-lite-v1:0", modelMap.BedrockModelID("gpt-4o-mini"))
+	assert.Equal(t, "meta.llama3-8b-instruct-v1:0", modelMap.BedrockModelID("mine"))
+
+	t.Setenv("MODEL_NAME_MAP", `{not json`)
+	_, err = NewModelMap("us-east-1")
+	assert.Error(t, err)
+}
+
+func TestDefaultAliases(t *testing.T) {
+	tests := []struct {
+		region   string
+		expected string
+	}{
+		{"us-west-2", "us.amazon.nova-lite-v1:0"},
+		{"us-gov-west-1", ""},
+		{"eu-central-1", "eu.amazon.nova-lite-v1:0"},
+		{"ap-northeast-1", "apac.amazon.nova-lite-v1:0"},
+		{"sa-east-1", "amazon.nova-lite-v1:0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DefaultAliases(tt.region)["gpt-4o-mini"].ModelID)
+		})
+	}
+
+	govCloud := DefaultAliases("us-gov-west-1")
+	assert.Equal(t, "us-gov.anthropic.claude-3-7-sonnet-20250219-v1:0", govCloud["gpt-4o"].ModelID)
+	assert.NotContains(t, govCloud, "gpt-3.5-turbo")
+}
+
+func TestModelMapRules(t *testing.T) {
+	t.Setenv("MODEL_NAME_MAP", `{"claude-exact": "anthropic.claude-v2"}`)
+	t.Setenv("MODEL_NAME_RULES", `[
+		{"match": "claude-*", "model": "anthropic.claude-3-5-sonnet-20241022-v2:0"},
+		{"match": "gpt-4*", "model": "us.anthropic.claude-sonnet-4-20250514-v1:0", "passthrough": ["top_k"]},
+		{"regex": "llama-?(\\d+)-(\\d+)b", "model": "meta.llama${1}-${2}b-instruct-v1:0"},
+		{"match": "nova-*", "model": "amazon.nova-$1-v1:0"},
+		{"match": "bedrock/*"}
+	]`)
+	t.Setenv("MODEL_NAME_DEFAULT", "")
+	t.Setenv("MODEL_NAME_STRICT", "")
+	modelMap, err := NewModelMap("us-east-1")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"claude-exact", "anthropic.claude-v2"},
+		{"claude-haiku", "anthropic.claude-3-5-sonnet-20241022-v2:0"},
+		{"gpt-4o", "us.anthropic.claude-sonnet-4-20250514-v1:0"},
+		{"gpt-4o-mini", "us.anthropic.claude-sonnet-4-20250514-v1:0"},
+		{"llama3-70b", "meta.llama3-70b-instruct-v1:0"},
+		{"llama-3-8b", "meta.llama3-8b-instruct-v1:0"},
+		{"my-llama3-8b", "my-llama3-8b"},
+		{"nova-pro", "amazon.nova-pro-v1:0"},
+		{"bedrock/custom", "bedrock/custom"},
+		{"o1", "us.anthropic.claude-sonnet-4-20250514-v1:0"},
+		{"unknown", "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, modelMap.BedrockModelID(tt.name))
+		})
+	}
+	assert.Equal(t, []string{"top_k"}, modelMap.Config("gpt-4").Passthrough)
+
+	t.Setenv("MODEL_NAME_RULES", `[{"match": "a", "regex": "a"}]`)
+	_, err = NewModelMap("us-east-1")
+	assert.Error(t, err)
+
+	t.Setenv("MODEL_NAME_RULES", `[{"regex": "(", "model": "a"}]`)
+	_, err = NewModelMap("us-east-1")
+	assert.Error(t, err)
+
+	t.Setenv("MODEL_NAME_RULES", `[{"model": "a"}]`)
+	_, err = NewModelMap("us-east-1")
+	assert.Error(t, err)
+}
+
+func TestModelMapStrict(t *testing.T) {
+	t.Setenv("MODEL_NAME_MAP", `{"mine": "meta.llama3-8b-instruct-v1:0"}`)
+	t.Setenv("MODEL_NAME_RULES", `[{"match": "claude-*", "model": "anthropic.claude-v2"}]`)
+	t.Setenv("MODEL_NAME_DEFAULT", "")
+	t.Setenv("MODEL_NAME_STRICT", "1")
+	modelMap, err := NewModelMap("us-east-1")
+	require.NoError(t, err)
+
+	for _, name := range []string{"mine", "claude-x", "gpt-4o"} {
+		_, err := modelMap.Resolve(name)
+		assert.NoError(t, err, name)
+	}
+	_, err = modelMap.Resolve("junk")
+	assert.ErrorIs(t, err, ErrModelNotFound)
+	assert.Equal(t, "junk", modelMap.BedrockModelID("junk"))
+
+	t.Setenv("MODEL_NAME_DEFAULT", `{"model": "amazon.nova-lite-v1:0", "passthrough": ["*"]}`)
+	modelMap, err = NewModelMap("us-east-1")
+	require.NoError(t, err)
+	config, err := modelMap.Resolve("junk")
+	require.NoError(t, err)
+	assert.Equal(t, "amazon.nova-lite-v1:0", config.ModelID)
+	assert.Equal(t, []string{"*"}, config.Passthrough)
+	assert.Equal(t, "us.amazon.nova-lite-v1:0", modelMap.BedrockModelID("gpt-4o-mini"))
+
+	t.Setenv("MODEL_NAME_DEFAULT", "amazon.nova-micro-v1:0")
+	modelMap, err = NewModelMap("us-east-1")
+	require.NoError(t, err)
+	assert.Equal(t, "amazon.nova-micro-v1:0", modelMap.BedrockModelID("junk"))
+}
+
+func TestModelMapInferenceProfiles(t *testing.T) {
+	t.Setenv("MODEL_NAME_MAP", `{"sonnet": "anthropic.claude-sonnet-4-20250514-v1:0"}`)
+	t.Setenv("MODEL_NAME_RULES", "")
+	t.Setenv("MODEL_NAME_DEFAULT", "")
+	t.Setenv("MODEL_NAME_STRICT", "")
+
+	t.Setenv("INFERENCE_PROFILES", "")
+	modelMap, err := NewModelMap("eu-west-1")
+	require.NoError(t, err)
+	assert.Equal(t, "eu.anthropic.claude-sonnet-4-20250514-v1:0", modelMap.BedrockModelID("sonnet"))
+	assert.Equal(t, "eu.anthropic.claude-opus-4-20250514-v1:0", modelMap.BedrockModelID("anthropic.claude-opus-4-20250514-v1:0"))
+
+	t.Setenv("INFERENCE_PROFILES", "off")
+	modelMap, err = NewModelMap("eu-west-1")
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic.claude-sonnet-4-20250514-v1:0", modelMap.BedrockModelID("sonnet"))
+
+	t.Setenv("INFERENCE_PROFILES", "require")
+	modelMap, err = NewModelMap("me-central-1")
+	require.NoError(t, err)
+	_, err = modelMap.Resolve("sonnet")
+	assert.ErrorIs(t, err, ErrProfileRequired)
+
+	t.Setenv("INFERENCE_PROFILES", "always")
+	_, err = NewModelMap("us-east-1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,181 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrock"
+	"github.com/aws/aws-sdk-go-v2/service/bedrock/types"
+)
+
+var ErrProfileRequired = errors.New("inference profile required")
+
+// ProfileMode says when a foundation model ID is replaced by the
+// cross-region inference profile of the region's geography.
+type ProfileMode string
+
+const (
+	// ProfileModeOff leaves model IDs alone.
+	ProfileModeOff ProfileMode = ""
+	// ProfileModeAuto uses a profile for models that cannot be invoked
+	// on demand.
+	ProfileModeAuto ProfileMode = "auto"
+	// ProfileModePrefer uses a profile for every model that has one.
+	ProfileModePrefer ProfileMode = "prefer"
+	// ProfileModeRequire uses a profile for every model, failing for models
+	// without one.
+	ProfileModeRequire ProfileMode = "require"
+)
+
+func parseProfileMode(value string) (ProfileMode, error) {
+	switch ProfileMode(value) {
+	case "":
+		return ProfileModeAuto, nil
+	case "off":
+		return ProfileModeOff, nil
+	case ProfileModeAuto, ProfileModePrefer, ProfileModeRequire:
+		return ProfileMode(value), nil
+	default:
+		return "", fmt.Errorf("unknown inference profile mode %q", value)
+	}
+}
+
+// knownProfileModels are the model families with geographic inference
+// profiles, and whether they can also be invoked on demand. Availability
+// differs between regions; discovered profiles take precedence.
+var knownProfileModels = []struct {
+	prefix   string
+	onDemand bool
+}{
+	{"anthropic.claude-3-haiku-", true},
+	{"anthropic.claude-3-sonnet-", true},
+	{"anthropic.claude-3-opus-", true},
+	{"anthropic.claude-3-5-", true},
+	{"anthropic.claude-3-7-", false},
+	{"anthropic.claude-sonnet-4", false},
+	{"anthropic.claude-opus-4", false},
+	{"amazon.nova-micro-", true},
+	{"amazon.nova-lite-", true},
+	{"amazon.nova-pro-", true},
+	{"amazon.nova-premier-", false},
+	{"meta.llama3-1-", true},
+	{"meta.llama3-2-", false},
+	{"meta.llama3-3-", false},
+	{"meta.llama4-", false},
+	{"deepseek.r1-", false},
+	{"mistral.pixtral-large-", false},
+}
+
+// noConverseModels are the prefixes of legacy text models that take and
+// return text but cannot be invoked with the Converse API.
+var noConverseModels = []string{
+	"amazon.titan-text-",
+	"amazon.titan-tg1-",
+	"cohere.command-text-",
+	"cohere.command-light-text-",
+	"ai21.j2-",
+}
+
+func supportsConverse(modelID string) bool {
+	for _, prefix := range noConverseModels {
+		if strings.HasPrefix(modelID, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// ProfileResolver picks the inference profile of a foundation model for
+// the region.
+type ProfileResolver struct {
+	Mode   ProfileMode
+	Region string
+	// Profiles and OnDemand, once discovered, hold the region's inference
+	// profile IDs and whether each foundation model can be invoked on
+	// demand. Until then knownProfileModels is used.
+	Profiles map[string]bool
+	OnDemand map[string]bool
+}
+
+// Resolve returns the model ID to invoke for modelID, which is unchanged
+// if it already is a profile or an ARN.
+func (r ProfileResolver) Resolve(modelID string) (string, error) {
+	if r.Mode == ProfileModeOff || strings.HasPrefix(modelID, "arn:") || isProfileGeography(strings.Split(modelID, ".")[0]) {
+		return modelID, nil
+	}
+
+	geography := regionGeography(r.Region)
+	required := r.Mode == ProfileModeRequire || r.needsProfile(modelID)
+	if geography != "" && r.hasProfile(geography, modelID) && (required || r.Mode == ProfileModePrefer) {
+		return geography + "." + modelID, nil
+	}
+	if required {
+		return "", fmt.Errorf("%w: model %s can only be invoked through an inference profile, and none is known for region %q", ErrProfileRequired, modelID, r.Region)
+	}
+	return modelID, nil
+}
+
+func (r ProfileResolver) hasProfile(geography, modelID string) bool {
+	if r.Profiles != nil {
+		return r.Profiles[geography+"."+modelID]
+	}
+	_, ok := knownProfileModel(modelID)
+	return ok
+}
+
+func (r ProfileResolver) needsProfile(modelID string) bool {
+	if r.OnDemand != nil {
+		onDemand, ok := r.OnDemand[modelID]
+		return ok && !onDemand
+	}
+	onDemand, ok := knownProfileModel(modelID)
+	return ok && !onDemand
+}
+
+func knownProfileModel(modelID string) (bool, bool) {
+	for _, model := range knownProfileModels {
+		if strings.HasPrefix(modelID, model.prefix) {
+			return model.onDemand, true
+		}
+	}
+	return false, false
+}
+
+// Discover replaces the built-in knowledge of inference profiles with the
+// system-defined profiles and foundation models of the region.
+func (r *ProfileResolver) Discover(ctx context.Context, lister CatalogLister) error {
+	foundationModels, err := lister.ListFoundationModels(ctx, &bedrock.ListFoundationModelsInput{})
+	if err != nil {
+		return fmt.Errorf("%w: failed to list foundation models", err)
+	}
+	onDemand := map[string]bool{}
+	for _, summary := range foundationModels.ModelSummaries {
+		onDemand[aws.ToString(summary.ModelId)] = slices.Contains(summary.InferenceTypesSupported, types.InferenceTypeOnDemand)
+	}
+
+	profiles := map[string]bool{}
+	input := &bedrock.ListInferenceProfilesInput{TypeEquals: types.InferenceProfileTypeSystemDefined}
+	for {
+		output, err := lister.ListInferenceProfiles(ctx, input)
+		if err != nil {
+			return fmt.Errorf("%w: failed to list inference profiles", err)
+		}
+		for _, profile := range output.InferenceProfileSummaries {
+			if profile.Status == types.InferenceProfileStatusActive {
+				profiles[aws.ToString(profile.InferenceProfileId)] = true
+			}
+		}
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	r.Profiles = profiles
+	r.OnDemand = onDemand
+	return nil
+}
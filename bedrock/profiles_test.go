@@ -0,0 +1,82 @@
+package bedrock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrock/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileResolver(t *testing.T) {
+	sonnet4 := "anthropic.claude-sonnet-4-20250514-v1:0"
+	haiku := "anthropic.claude-3-haiku-20240307-v1:0"
+	titan := "amazon.titan-embed-text-v2:0"
+
+	tests := []struct {
+		name     string
+		mode     ProfileMode
+		region   string
+		modelID  string
+		expected string
+		err      error
+	}{
+		{"auto profile only", ProfileModeAuto, "us-west-2", sonnet4, "us." + sonnet4, nil},
+		{"auto on demand", ProfileModeAuto, "us-west-2", haiku, haiku, nil},
+		{"auto eu", ProfileModeAuto, "eu-central-1", sonnet4, "eu." + sonnet4, nil},
+		{"auto unknown geography", ProfileModeAuto, "sa-east-1", sonnet4, "", ErrProfileRequired},
+		{"already a profile", ProfileModeAuto, "eu-west-1", "us." + sonnet4, "us." + sonnet4, nil},
+		{"arn", ProfileModeRequire, "us-east-1", "arn:aws:bedrock:us-east-1:123:custom-model/x", "arn:aws:bedrock:us-east-1:123:custom-model/x", nil},
+		{"prefer", ProfileModePrefer, "ap-southeast-2", haiku, "apac." + haiku, nil},
+		{"prefer without profile", ProfileModePrefer, "us-east-1", titan, titan, nil},
+		{"require", ProfileModeRequire, "us-east-1", haiku, "us." + haiku, nil},
+		{"require without profile", ProfileModeRequire, "us-east-1", titan, "", ErrProfileRequired},
+		{"off", ProfileModeOff, "us-east-1", sonnet4, sonnet4, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modelID, err := ProfileResolver{Mode: tt.mode, Region: tt.region}.Resolve(tt.modelID)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, modelID)
+		})
+	}
+}
+
+func TestProfileResolverDiscover(t *testing.T) {
+	text := []types.ModelModality{types.ModelModalityText}
+	lister := &mockCatalogLister{
+		models: []types.FoundationModelSummary{
+			foundationModel("anthropic.new-model-v1:0", text, text, types.InferenceTypeProvisioned),
+			foundationModel("anthropic.claude-3-haiku", text, text, types.InferenceTypeOnDemand),
+			foundationModel("anthropic.claude-sonnet-4", text, text, types.InferenceTypeProvisioned),
+		},
+		profiles: [][]types.InferenceProfileSummary{
+			{inferenceProfile("eu.anthropic.new-model-v1:0")},
+			{inferenceProfile("eu.anthropic.claude-3-haiku")},
+		},
+	}
+
+	resolver := ProfileResolver{Mode: ProfileModeAuto, Region: "eu-west-3"}
+	require.NoError(t, resolver.Discover(context.Background(), lister))
+
+	modelID, err := resolver.Resolve("anthropic.new-model-v1:0")
+	require.NoError(t, err)
+	assert.Equal(t, "eu.anthropic.new-model-v1:0", modelID)
+
+	modelID, err = resolver.Resolve("anthropic.claude-3-haiku")
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic.claude-3-haiku", modelID)
+
+	_, err = resolver.Resolve("anthropic.claude-sonnet-4")
+	assert.ErrorIs(t, err, ErrProfileRequired)
+
+	resolver.Mode = ProfileModePrefer
+	modelID, err = resolver.Resolve("anthropic.claude-3-haiku")
+	require.NoError(t, err)
+	assert.Equal(t, "eu.anthropic.claude-3-haiku", modelID)
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the sidecar's configuration file, in YAML or JSON. Environment
+// variables take precedence over it.
+type Config struct {
+	Listen  Listen                `yaml:"listen"`
+	Logging Logging               `yaml:"logging"`
+	Models  bedrock.ModelSettings `yaml:"models"`
+	Auth    Auth                  `yaml:"auth"`
+}
+
+type Listen struct {
+	Port              string   `yaml:"port"`
+	ReadTimeout       Duration `yaml:"read_timeout"`
+	WriteTimeout      Duration `yaml:"write_timeout"`
+	IdleTimeout       Duration `yaml:"idle_timeout"`
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout"`
+	// The stream settings apply to each streamed response, so unlike the
+	// others they take effect on reload.
+	StreamMaxDuration       Duration `yaml:"stream_max_duration"`
+	StreamIdleTimeout       Duration `yaml:"stream_idle_timeout"`
+	StreamHeartbeatInterval Duration `yaml:"stream_heartbeat_interval"`
+}
+
+// NeedsRestart reports whether the HTTP server settings of l and other
+// differ, which only apply after a restart.
+func (l Listen) NeedsRestart(other Listen) bool {
+	return l.Port != other.Port ||
+		l.ReadTimeout != other.ReadTimeout ||
+		l.WriteTimeout != other.WriteTimeout ||
+		l.IdleTimeout != other.IdleTimeout ||
+		l.ReadHeaderTimeout != other.ReadHeaderTimeout
+}
+
+type Logging struct {
+	// Level is debug, info, warn or error.
+	Level string `yaml:"level"`
+}
+
+type Auth struct {
+	// APIKeys, if set, are the keys clients must send as a bearer token or
+	// in an x-api-key or api-key header.
+	APIKeys []string `yaml:"api_keys"`
+}
+
+// Duration is a Go duration such as "90s" or "5m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	duration, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q", node.Line, node.Value)
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+func Default() Config {
+	return Config{
+		Listen: Listen{
+			Port:              "8080",
+			ReadTimeout:       Duration(60 * time.Second),
+			WriteTimeout:      Duration(60 * time.Second),
+			IdleTimeout:       Duration(60 * time.Second),
+			ReadHeaderTimeout: Duration(2 * time.Second),
+			// The handler's defaults.
+			StreamMaxDuration:       Duration(15 * time.Minute),
+			StreamIdleTimeout:       Duration(2 * time.Minute),
+			StreamHeartbeatInterval: Duration(15 * time.Second),
+		},
+		Logging: Logging{Level: "warn"},
+	}
+}
+
+// Load reads the configuration file at path, if any, over the defaults and
+// then applies the environment.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("%w: unable to read config file", err)
+		}
+		if err := cfg.decode(data); err != nil {
+			return Config{}, fmt.Errorf("%w: invalid config file %s", err, path)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.validate(nil); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) decode(data []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return err
+	}
+	return c.validate(&root)
+}
+
+// applyEnv overrides the configuration with the environment variables that
+// predate the configuration file.
+func (c *Config) applyEnv() error {
+	if port := os.Getenv("PORT"); port != "" {
+		c.Listen.Port = port
+	}
+	for name, duration := range map[string]*Duration{
+		"READ_TIMEOUT":        &c.Listen.ReadTimeout,
+		"WRITE_TIMEOUT":       &c.Listen.WriteTimeout,
+		"IDLE_TIMEOUT":        &c.Listen.IdleTimeout,
+		"READ_HEADER_TIMEOUT": &c.Listen.ReadHeaderTimeout,
+
+		"STREAM_MAX_DURATION":       &c.Listen.StreamMaxDuration,
+		"STREAM_IDLE_TIMEOUT":       &c.Listen.StreamIdleTimeout,
+		"STREAM_HEARTBEAT_INTERVAL": &c.Listen.StreamHeartbeatInterval,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%w: unable to parse %s", err, name)
+		}
+		*duration = Duration(parsed)
+	}
+
+	if os.Getenv("DEBUG") != "" {
+		c.Logging.Level = "debug"
+	}
+
+	if value := strings.TrimSpace(os.Getenv("API_KEYS")); value != "" {
+		c.Auth.APIKeys = nil
+		for _, key := range strings.Split(value, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				c.Auth.APIKeys = append(c.Auth.APIKeys, key)
+			}
+		}
+	}
+
+	return c.Models.ApplyEnv()
+}
+
+// validate checks the values that decoding cannot. Errors give the line of
+// the offending value when root, the parsed file, is set.
+func (c Config) validate(root *yaml.Node) error {
+	if port, err := strconv.Atoi(c.Listen.Port); err != nil || port < 1 || port > 65535 {
+		return locate(root, fmt.Errorf("invalid port %q", c.Listen.Port), "listen", "port")
+	}
+	if _, err := c.Logging.SlogLevel(); err != nil {
+		return locate(root, err, "logging", "level")
+	}
+	if _, err := c.Models.ModelMap(""); err != nil {
+		return locate(root, err, "models", "inference_profiles")
+	}
+	for _, key := range c.Auth.APIKeys {
+		if key == "" {
+			return locate(root, errors.New("API keys must not be empty"), "auth", "api_keys")
+		}
+	}
+	return nil
+}
+
+// locate prefixes err with the line of the value at path in root, and the
+// path itself.
+func locate(root *yaml.Node, err error, path ...string) error {
+	err = fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+	if root == nil {
+		return err
+	}
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, key := range path {
+		var next *yaml.Node
+		for i := 0; node.Kind == yaml.MappingNode && i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return err
+		}
+		node = next
+	}
+	return fmt.Errorf("line %d: %w", node.Line, err)
+}
+
+func (l Logging) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", l.Level)
+	}
+	return level, nil
+}
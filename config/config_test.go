@@ -0,0 +1,252 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func clearEnv(t *testing.T) {
+	for _, name := range []string{
+		"PORT", "READ_TIMEOUT", "WRITE_TIMEOUT", "IDLE_TIMEOUT", "READ_HEADER_TIMEOUT", "DEBUG", "API_KEYS",
+		"STREAM_MAX_DURATION", "STREAM_IDLE_TIMEOUT", "STREAM_HEARTBEAT_INTERVAL",
+		"MODEL_NAME_MAP", "MODEL_NAME_RULES", "MODEL_NAME_DEFAULT", "MODEL_NAME_STRICT", "INFERENCE_PROFILES",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+const yamlConfig = `
+listen:
+  port: 9090
+  read_timeout: 30s
+  stream_idle_timeout: 5m
+logging:
+  level: info
+models:
+  aliases:
+    gpt-4o: anthropic.claude-3-5-sonnet-20241022-v2:0
+    claude:
+      model: anthropic.claude-3-5-haiku-20241022-v1:0
+      inference:
+        max_tokens: {default: 4096, max: 8192}
+        strip: [top_p]
+  rules:
+    - match: "llama-*"
+      model: "meta.llama$1-instruct-v1:0"
+  default: amazon.nova-lite-v1:0
+  inference_profiles: "off"
+auth:
+  api_keys: [secret]
+`
+
+func TestLoad(t *testing.T) {
+	clearEnv(t)
+
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := Load("")
+		require.NoError(t, err)
+		assert.Equal(t, Default(), cfg)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		cfg, err := Load(writeFile(t, "config.yaml", yamlConfig))
+		require.NoError(t, err)
+		assert.Equal(t, "9090", cfg.Listen.Port)
+		assert.Equal(t, Duration(30*time.Second), cfg.Listen.ReadTimeout)
+		assert.Equal(t, Duration(60*time.Second), cfg.Listen.WriteTimeout)
+		assert.Equal(t, Duration(5*time.Minute), cfg.Listen.StreamIdleTimeout)
+		assert.Equal(t, Duration(15*time.Minute), cfg.Listen.StreamMaxDuration)
+		assert.Equal(t, "info", cfg.Logging.Level)
+		assert.Equal(t, []string{"secret"}, cfg.Auth.APIKeys)
+
+		modelMap, err := cfg.Models.ModelMap("us-east-1")
+		require.NoError(t, err)
+		assert.Equal(t, "anthropic.claude-3-5-sonnet-20241022-v2:0", modelMap.BedrockModelID("gpt-4o"))
+		assert.Equal(t, "meta.llama3-8b-instruct-v1:0", modelMap.BedrockModelID("llama-3-8b"))
+		assert.Equal(t, "amazon.nova-lite-v1:0", modelMap.BedrockModelID("unknown"))
+		claude := modelMap.Config("claude")
+		require.NotNil(t, claude.Inference)
+		assert.Equal(t, 8192, *claude.Inference.MaxTokens.Max)
+		assert.Equal(t, []string{"top_p"}, claude.Inference.Strip)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		cfg, err := Load(writeFile(t, "config.json", `{
+			"listen": {"port": "9091"},
+			"models": {"aliases": {"gpt-4o": {"model": "amazon.nova-pro-v1:0"}}, "strict": true}
+		}`))
+		require.NoError(t, err)
+		assert.Equal(t, "9091", cfg.Listen.Port)
+		assert.True(t, cfg.Models.Strict)
+		assert.Equal(t, "amazon.nova-pro-v1:0", cfg.Models.Aliases["gpt-4o"].ModelID)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		cfg, err := Load(writeFile(t, "config.yaml", ""))
+		require.NoError(t, err)
+		assert.Equal(t, Default(), cfg)
+	})
+}
+
+func TestLoadEnvPrecedence(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("PORT", "7070")
+	t.Setenv("DEBUG", "1")
+	t.Setenv("STREAM_IDLE_TIMEOUT", "30s")
+	t.Setenv("API_KEYS", "one, two")
+	t.Setenv("MODEL_NAME_MAP", `{"gpt-4o": "amazon.nova-micro-v1:0"}`)
+	t.Setenv("MODEL_NAME_RULES", `[{"match": "llama-3-8b", "model": "meta.llama3-1-8b-instruct-v1:0"}]`)
+
+	cfg, err := Load(writeFile(t, "config.yaml", yamlConfig))
+	require.NoError(t, err)
+	assert.Equal(t, "7070", cfg.Listen.Port)
+	assert.Equal(t, "debug", cfg.Logging.Level)
+	assert.Equal(t, Duration(30*time.Second), cfg.Listen.StreamIdleTimeout)
+	assert.Equal(t, []string{"one", "two"}, cfg.Auth.APIKeys)
+
+	modelMap, err := cfg.Models.ModelMap("us-east-1")
+	require.NoError(t, err)
+	assert.Equal(t, "amazon.nova-micro-v1:0", modelMap.BedrockModelID("gpt-4o"))
+	assert.Equal(t, "anthropic.claude-3-5-haiku-20241022-v1:0", modelMap.BedrockModelID("claude"))
+	assert.Equal(t, "meta.llama3-1-8b-instruct-v1:0", modelMap.BedrockModelID("llama-3-8b"))
+	assert.Equal(t, "meta.llama3-70b-instruct-v1:0", modelMap.BedrockModelID("llama-3-70b"))
+
+	strictConfig := writeFile(t, "config.yaml", "models:\n  strict: true\n")
+	t.Setenv("MODEL_NAME_STRICT", "false")
+	cfg, err = Load(strictConfig)
+	require.NoError(t, err)
+	assert.False(t, cfg.Models.Strict)
+
+	t.Setenv("MODEL_NAME_STRICT", "sometimes")
+	_, err = Load(strictConfig)
+	assert.ErrorContains(t, err, "MODEL_NAME_STRICT")
+	t.Setenv("MODEL_NAME_STRICT", "")
+
+	t.Setenv("READ_TIMEOUT", "soon")
+	_, err = Load("")
+	assert.ErrorContains(t, err, "READ_TIMEOUT")
+}
+
+func TestLoadErrors(t *testing.T) {
+	clearEnv(t)
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "syntax",
+			content:  "listen:\n  port: 9090\n\tread_timeout: 5s\n",
+			expected: "yaml: line",
+		},
+		{
+			name:     "unknown field",
+			content:  "listen:\n  port: 9090\n  timeout: 5s\n",
+			expected: "line 3: field timeout not found",
+		},
+		{
+			name:     "unknown model field",
+			content:  "models:\n  aliases:\n    gpt-4o:\n      model: amazon.nova-pro-v1:0\n      passthru: [top_k]\n",
+			expected: "line 5: field passthru not found",
+		},
+		{
+			name:     "unknown inference field",
+			content:  "models:\n  default:\n    model: amazon.nova-pro-v1:0\n    inference:\n      max_tokens: {maximum: 10}\n",
+			expected: "line 5: field maximum not found",
+		},
+		{
+			name:     "unknown rule field",
+			content:  "models:\n  rules:\n    - match: \"*\"\n      modle: amazon.nova-pro-v1:0\n",
+			expected: "line 4: field modle not found",
+		},
+		{
+			name:     "invalid regex",
+			content:  "models:\n  rules:\n    - regex: \"(\"\n      model: amazon.nova-pro-v1:0\n",
+			expected: "line 3: ",
+		},
+		{
+			name:     "wrong type",
+			content:  "models:\n  strict: maybe\n",
+			expected: "line 2: cannot unmarshal",
+		},
+		{
+			name:     "invalid duration",
+			content:  "listen:\n  idle_timeout: 60\n",
+			expected: "line 2: invalid duration",
+		},
+		{
+			name:     "invalid port",
+			content:  "\nlisten:\n  port: http\n",
+			expected: "line 3: listen.port: invalid port",
+		},
+		{
+			name:     "invalid log level",
+			content:  "logging:\n  level: loud\n",
+			expected: "line 2: logging.level: unknown log level",
+		},
+		{
+			name:     "invalid inference profile mode",
+			content:  "models:\n  inference_profiles: always\n",
+			expected: "line 2: models.inference_profiles",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, "config.yaml", tt.content)
+			_, err := Load(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+			assert.Contains(t, err.Error(), path)
+		})
+	}
+
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestListenNeedsRestart(t *testing.T) {
+	listen := Default().Listen
+
+	changed := listen
+	changed.StreamIdleTimeout = Duration(time.Minute)
+	assert.False(t, changed.NeedsRestart(listen))
+
+	changed.WriteTimeout = Duration(5 * time.Minute)
+	assert.True(t, changed.NeedsRestart(listen))
+}
+
+func TestWatch(t *testing.T) {
+	path := writeFile(t, "config.yaml", "logging:\n  level: info\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloads := make(chan struct{}, 1)
+	go Watch(ctx, path, 10*time.Millisecond, func() {
+		select {
+		case reloads <- struct{}{}:
+		default:
+		}
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, os.WriteFile(path, []byte("logging:\n  level: debug\n"), 0o600))
+	select {
+	case <-reloads:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a reload after the file changed")
+	}
+}
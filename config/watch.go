@@ -0,0 +1,56 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Watch calls reload whenever the process receives SIGHUP or the file at
+// path changes, until ctx is done. The file is polled every interval, which
+// also notices a file replaced by a rename, as Kubernetes does for mounted
+// ConfigMaps.
+func Watch(ctx context.Context, path string, interval time.Duration, reload func()) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := stat(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			slog.Info("Reloading config on SIGHUP", "path", path)
+			last = stat(path)
+			reload()
+		case <-ticker.C:
+			current := stat(path)
+			if current == last {
+				continue
+			}
+			last = current
+			slog.Info("Reloading changed config", "path", path)
+			reload()
+		}
+	}
+}
+
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func stat(path string) fileVersion {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}
+	}
+	return fileVersion{modTime: info.ModTime(), size: info.Size()}
+}
@@ -0,0 +1,541 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// AnthropicRequest is an Anthropic Messages API request. It is run as a
+// chat completion of the same conversation.
+type AnthropicRequest struct {
+	Model         string                 `json:"model"`
+	MaxTokens     int                    `json:"max_tokens"`
+	System        AnthropicContent       `json:"system,omitempty"`
+	Messages      []AnthropicMessage     `json:"messages"`
+	StopSequences []string               `json:"stop_sequences,omitempty"`
+	Stream        bool                   `json:"stream,omitempty"`
+	Temperature   *float64               `json:"temperature,omitempty"`
+	TopP          *float64               `json:"top_p,omitempty"`
+	TopK          *int                   `json:"top_k,omitempty"`
+	Tools         []AnthropicTool        `json:"tools,omitempty"`
+	ToolChoice    *AnthropicToolChoice   `json:"tool_choice,omitempty"`
+	Thinking      map[string]interface{} `json:"thinking,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type AnthropicMessage struct {
+	Role    string           `json:"role"`
+	Content AnthropicContent `json:"content"`
+}
+
+// AnthropicContent is a list of content blocks, which may be sent as a
+// plain string.
+type AnthropicContent []AnthropicContentBlock
+
+func (c *AnthropicContent) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*c = AnthropicContent{{Type: "text", Text: text}}
+		return nil
+	}
+
+	var blocks []AnthropicContentBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return fmt.Errorf("%w: content must be a string or an array of content blocks", ErrInvalidContent)
+	}
+	*c = blocks
+	return nil
+}
+
+func (c AnthropicContent) text() string {
+	texts := make([]string, 0, len(c))
+	for _, block := range c {
+		if block.Type == "text" {
+			texts = append(texts, block.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+type AnthropicContentBlock struct {
+	Type   string           `json:"type"`
+	Text   string           `json:"text,omitempty"`
+	Source *AnthropicSource `json:"source,omitempty"`
+	Title  string           `json:"title,omitempty"`
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// tool_result
+	ToolUseID string           `json:"tool_use_id,omitempty"`
+	Content   AnthropicContent `json:"content,omitempty"`
+	IsError   bool             `json:"is_error,omitempty"`
+	// thinking and redacted_thinking
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
+type AnthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type AnthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type AnthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type AnthropicResponse struct {
+	ID           string                  `json:"id"`
+	Type         string                  `json:"type"`
+	Role         string                  `json:"role"`
+	Model        string                  `json:"model"`
+	Content      []AnthropicContentBlock `json:"content"`
+	StopReason   *string                 `json:"stop_reason"`
+	StopSequence *string                 `json:"stop_sequence"`
+	Usage        AnthropicUsage          `json:"usage"`
+}
+
+type AnthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+func (r AnthropicRequest) ToOpenAIRequest() (OpenAIRequest, error) {
+	if r.MaxTokens <= 0 {
+		return OpenAIRequest{}, fmt.Errorf("%w: max_tokens is required", ErrInvalidContent)
+	}
+
+	openAIReq := OpenAIRequest{
+		Model:       r.Model,
+		MaxTokens:   r.MaxTokens,
+		Stop:        r.StopSequences,
+		Stream:      r.Stream,
+		Temperature: r.Temperature,
+		TopP:        r.TopP,
+	}
+	if r.Thinking != nil || r.TopK != nil {
+		openAIReq.ModelFields = map[string]interface{}{}
+	}
+	if r.Thinking != nil {
+		openAIReq.ModelFields["thinking"] = r.Thinking
+	}
+	if r.TopK != nil {
+		openAIReq.ModelFields["top_k"] = *r.TopK
+	}
+
+	if system := r.System.text(); system != "" {
+		openAIReq.Messages = append(openAIReq.Messages, OpenAIMessage{Role: "system", Content: system})
+	}
+	for _, msg := range r.Messages {
+		messages, err := anthropicMessageToOpenAI(msg)
+		if err != nil {
+			return OpenAIRequest{}, err
+		}
+		openAIReq.Messages = append(openAIReq.Messages, messages...)
+	}
+
+	for _, tool := range r.Tools {
+		openAIReq.Tools = append(openAIReq.Tools, OpenAITool{
+			Type: "function",
+			Function: OpenAIFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+
+	if r.ToolChoice != nil {
+		switch r.ToolChoice.Type {
+		case "auto", "none":
+			openAIReq.ToolChoice = &OpenAIToolChoice{Type: r.ToolChoice.Type}
+		case "any":
+			openAIReq.ToolChoice = &OpenAIToolChoice{Type: "required"}
+		case "tool":
+			openAIReq.ToolChoice = &OpenAIToolChoice{Type: "function", Function: r.ToolChoice.Name}
+		default:
+			return OpenAIRequest{}, fmt.Errorf("%w: unsupported tool_choice type %q", ErrInvalidToolCall, r.ToolChoice.Type)
+		}
+	}
+	return openAIReq, nil
+}
+
+// anthropicMessageToOpenAI converts one message. Tool results become tool
+// messages of their own, sent before the rest of the user's turn.
+func anthropicMessageToOpenAI(msg AnthropicMessage) ([]OpenAIMessage, error) {
+	var messages []OpenAIMessage
+	result := OpenAIMessage{Role: msg.Role}
+	var texts []string
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "text":
+			texts = append(texts, block.Text)
+			result.ContentParts = append(result.ContentParts, OpenAIContentPart{Type: "text", Text: block.Text})
+		case "image":
+			url, err := block.Source.url()
+			if err != nil {
+				return nil, err
+			}
+			result.ContentParts = append(result.ContentParts, OpenAIContentPart{Type: "image_url", ImageURL: &OpenAIImageURL{URL: url}})
+		case "document":
+			part, err := block.documentPart()
+			if err != nil {
+				return nil, err
+			}
+			result.ContentParts = append(result.ContentParts, part)
+		case "tool_use":
+			arguments := string(block.Input)
+			if arguments == "" {
+				arguments = "{}"
+			}
+			result.ToolCalls = append(result.ToolCalls, OpenAIToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: OpenAIFunctionCall{Name: block.Name, Arguments: arguments},
+			})
+		case "tool_result":
+			content := block.Content.text()
+			if block.IsError {
+				content = "Error: " + content
+			}
+			messages = append(messages, OpenAIMessage{Role: "tool", ToolCallID: block.ToolUseID, Content: content})
+		case "thinking":
+			result.Reasoning = append(result.Reasoning, ReasoningBlock{Text: block.Thinking, Signature: block.Signature})
+		case "redacted_thinking":
+			data, err := base64.StdEncoding.DecodeString(block.Data)
+			if err != nil {
+				return nil, fmt.Errorf("%w: redacted_thinking data is not valid base64", ErrInvalidContent)
+			}
+			result.Reasoning = append(result.Reasoning, ReasoningBlock{Redacted: data})
+		default:
+			return nil, fmt.Errorf("%w: unsupported content block type %q", ErrInvalidContent, block.Type)
+		}
+	}
+
+	// Assistant turns are sent as text after their thinking, and user turns
+	// that only carry tool results are left out.
+	if msg.Role == "assistant" {
+		result.Content = strings.Join(texts, "\n")
+		result.ContentParts = nil
+		return append(messages, result), nil
+	}
+	if len(result.ContentParts) > 0 || len(messages) == 0 {
+		messages = append(messages, result)
+	}
+	return messages, nil
+}
+
+func (s *AnthropicSource) url() (string, error) {
+	if s == nil {
+		return "", fmt.Errorf("%w: image is missing source", ErrInvalidContent)
+	}
+	switch s.Type {
+	case "base64":
+		return "data:" + s.MediaType + ";base64," + s.Data, nil
+	case "url":
+		return s.URL, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported source type %q", ErrInvalidContent, s.Type)
+	}
+}
+
+func (b AnthropicContentBlock) documentPart() (OpenAIContentPart, error) {
+	if b.Source == nil {
+		return OpenAIContentPart{}, fmt.Errorf("%w: document is missing source", ErrInvalidContent)
+	}
+	filename := b.Title
+	if filename == "" {
+		filename = "document"
+	}
+	switch b.Source.Type {
+	case "base64":
+		return OpenAIContentPart{
+			Type: "file",
+			File: &OpenAIFile{Filename: filename, FileData: "data:" + b.Source.MediaType + ";base64," + b.Source.Data},
+		}, nil
+	case "text":
+		return OpenAIContentPart{Type: "text", Text: b.Source.Data}, nil
+	default:
+		return OpenAIContentPart{}, fmt.Errorf("%w: unsupported document source type %q", ErrInvalidContent, b.Source.Type)
+	}
+}
+
+func anthropicID(chatID string) string {
+	return "msg_" + strings.TrimPrefix(chatID, "chatcmpl-")
+}
+
+func anthropicStopReason(finishReason string) string {
+	switch finishReason {
+	case "length":
+		return "max_tokens"
+	case "tool_calls":
+		return "tool_use"
+	case "content_filter":
+		return "refusal"
+	default:
+		return "end_turn"
+	}
+}
+
+// makeAnthropicUsage reports the input tokens without the cached ones,
+// which Anthropic counts separately.
+func makeAnthropicUsage(usage Usage) AnthropicUsage {
+	result := AnthropicUsage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+	}
+	if usage.PromptTokensDetails != nil {
+		result.CacheReadInputTokens = usage.PromptTokensDetails.CachedTokens
+		result.CacheCreationInputTokens = usage.PromptTokensDetails.CacheWriteTokens
+		result.InputTokens -= result.CacheReadInputTokens + result.CacheCreationInputTokens
+	}
+	return result
+}
+
+func ToAnthropicResponse(resp OpenAIResponse, model string) AnthropicResponse {
+	result := AnthropicResponse{
+		ID:      anthropicID(resp.ID),
+		Type:    "message",
+		Role:    "assistant",
+		Model:   model,
+		Content: []AnthropicContentBlock{},
+		Usage:   makeAnthropicUsage(resp.Usage),
+	}
+	if len(resp.Choices) == 0 {
+		return result
+	}
+
+	choice := resp.Choices[0]
+	for _, reasoning := range choice.Message.Reasoning {
+		result.Content = append(result.Content, anthropicThinkingBlock(reasoning))
+	}
+	if choice.Message.Content != "" {
+		result.Content = append(result.Content, AnthropicContentBlock{Type: "text", Text: choice.Message.Content})
+	}
+	for _, call := range choice.Message.ToolCalls {
+		result.Content = append(result.Content, AnthropicContentBlock{
+			Type:  "tool_use",
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Input: json.RawMessage(call.Function.Arguments),
+		})
+	}
+	stopReason := anthropicStopReason(choice.FinishReason)
+	result.StopReason = &stopReason
+	return result
+}
+
+func anthropicThinkingBlock(reasoning ReasoningBlock) AnthropicContentBlock {
+	if reasoning.Redacted != nil {
+		return AnthropicContentBlock{Type: "redacted_thinking", Data: base64.StdEncoding.EncodeToString(reasoning.Redacted)}
+	}
+	return AnthropicContentBlock{Type: "thinking", Thinking: reasoning.Text, Signature: reasoning.Signature}
+}
+
+// AnthropicStreamEvent is one of the typed server-sent events of a streamed
+// message. Only the fields of its type are set.
+type AnthropicStreamEvent struct {
+	Type         string                 `json:"type"`
+	Message      *AnthropicResponse     `json:"message,omitempty"`
+	Index        *int                   `json:"index,omitempty"`
+	ContentBlock *AnthropicContentBlock `json:"content_block,omitempty"`
+	Delta        *AnthropicDelta        `json:"delta,omitempty"`
+	Usage        *AnthropicUsage        `json:"usage,omitempty"`
+}
+
+// AnthropicDelta is the delta of a content_block_delta or message_delta
+// event.
+type AnthropicDelta struct {
+	Type         string  `json:"type,omitempty"`
+	Text         string  `json:"text,omitempty"`
+	Thinking     string  `json:"thinking,omitempty"`
+	Signature    string  `json:"signature,omitempty"`
+	PartialJSON  *string `json:"partial_json,omitempty"`
+	StopReason   *string `json:"stop_reason,omitempty"`
+	StopSequence *string `json:"stop_sequence,omitempty"`
+}
+
+// MarshalJSON includes the empty text, thinking or input that
+// content_block_start events carry.
+func (b AnthropicContentBlock) MarshalJSON() ([]byte, error) {
+	type alias AnthropicContentBlock
+	switch b.Type {
+	case "text":
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}{b.Type, b.Text})
+	case "thinking":
+		return json.Marshal(struct {
+			Type      string `json:"type"`
+			Thinking  string `json:"thinking"`
+			Signature string `json:"signature"`
+		}{b.Type, b.Thinking, b.Signature})
+	case "tool_use":
+		input := b.Input
+		if len(input) == 0 {
+			input = json.RawMessage("{}")
+		}
+		return json.Marshal(struct {
+			Type  string          `json:"type"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		}{b.Type, b.ID, b.Name, input})
+	default:
+		return json.Marshal(alias(b))
+	}
+}
+
+// AnthropicStream turns the chat chunks of a streamed message into
+// Anthropic events, with a content block for the thinking, the text and
+// each tool call.
+type AnthropicStream struct {
+	model        string
+	started      bool
+	block        int
+	blockType    string
+	calls        map[int64]int
+	finishReason string
+}
+
+func NewAnthropicStream(model string) *AnthropicStream {
+	return &AnthropicStream{model: model, block: -1, calls: map[int64]int{}}
+}
+
+func (s *AnthropicStream) start(chatID string) []AnthropicStreamEvent {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	return []AnthropicStreamEvent{{
+		Type: "message_start",
+		Message: &AnthropicResponse{
+			ID:      anthropicID(chatID),
+			Type:    "message",
+			Role:    "assistant",
+			Model:   s.model,
+			Content: []AnthropicContentBlock{},
+		},
+	}}
+}
+
+// Chunk converts one chat chunk into the events it causes.
+func (s *AnthropicStream) Chunk(chunk openai.ChatCompletionChunk) []AnthropicStreamEvent {
+	events := s.start(chunk.ID)
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" {
+			if s.blockType != "text" {
+				events = append(events, s.openBlock(AnthropicContentBlock{Type: "text"})...)
+			}
+			index := s.block
+			events = append(events, AnthropicStreamEvent{
+				Type:  "content_block_delta",
+				Index: &index,
+				Delta: &AnthropicDelta{Type: "text_delta", Text: choice.Delta.Content},
+			})
+		}
+		for _, call := range choice.Delta.ToolCalls {
+			index, ok := s.calls[call.Index]
+			if !ok {
+				events = append(events, s.openBlock(AnthropicContentBlock{
+					Type: "tool_use",
+					ID:   call.ID,
+					Name: call.Function.Name,
+				})...)
+				index = s.block
+				s.calls[call.Index] = index
+			}
+			if call.Function.Arguments != "" {
+				arguments := call.Function.Arguments
+				events = append(events, AnthropicStreamEvent{
+					Type:  "content_block_delta",
+					Index: &index,
+					Delta: &AnthropicDelta{Type: "input_json_delta", PartialJSON: &arguments},
+				})
+			}
+		}
+		if choice.FinishReason != "" {
+			s.finishReason = string(choice.FinishReason)
+		}
+	}
+	return events
+}
+
+// Reasoning converts a piece of the model's thinking into the events it
+// causes.
+func (s *AnthropicStream) Reasoning(identity StreamIdentity, delta ReasoningDelta) []AnthropicStreamEvent {
+	events := s.start(identity.ID)
+	if delta.Redacted != nil {
+		// Redacted thinking arrives whole, as the start of its own block.
+		return append(events, s.openBlock(anthropicThinkingBlock(ReasoningBlock(delta)))...)
+	}
+	if s.blockType != "thinking" {
+		events = append(events, s.openBlock(AnthropicContentBlock{Type: "thinking"})...)
+	}
+	index := s.block
+	if delta.Text != "" {
+		events = append(events, AnthropicStreamEvent{
+			Type:  "content_block_delta",
+			Index: &index,
+			Delta: &AnthropicDelta{Type: "thinking_delta", Thinking: delta.Text},
+		})
+	}
+	if delta.Signature != "" {
+		events = append(events, AnthropicStreamEvent{
+			Type:  "content_block_delta",
+			Index: &index,
+			Delta: &AnthropicDelta{Type: "signature_delta", Signature: delta.Signature},
+		})
+	}
+	return events
+}
+
+func (s *AnthropicStream) openBlock(block AnthropicContentBlock) []AnthropicStreamEvent {
+	events := s.closeBlock()
+	s.block++
+	s.blockType = block.Type
+	index := s.block
+	return append(events, AnthropicStreamEvent{Type: "content_block_start", Index: &index, ContentBlock: &block})
+}
+
+func (s *AnthropicStream) closeBlock() []AnthropicStreamEvent {
+	if s.blockType == "" {
+		return nil
+	}
+	s.blockType = ""
+	index := s.block
+	return []AnthropicStreamEvent{{Type: "content_block_stop", Index: &index}}
+}
+
+// Done closes the last content block and ends the message.
+func (s *AnthropicStream) Done(identity StreamIdentity, usage Usage) []AnthropicStreamEvent {
+	events := append(s.start(identity.ID), s.closeBlock()...)
+	stopReason := anthropicStopReason(s.finishReason)
+	anthropicUsage := makeAnthropicUsage(usage)
+	return append(events,
+		AnthropicStreamEvent{Type: "message_delta", Delta: &AnthropicDelta{StopReason: &stopReason}, Usage: &anthropicUsage},
+		AnthropicStreamEvent{Type: "message_stop"},
+	)
+}
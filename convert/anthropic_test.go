@@ -0,0 +1,177 @@
+package convert
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicRequestToOpenAIRequest(t *testing.T) {
+	var req AnthropicRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "claude-sonnet",
+		"max_tokens": 1024,
+		"system": [{"type": "text", "text": "Be brief."}],
+		"stop_sequences": ["END"],
+		"top_k": 5,
+		"thinking": {"type": "enabled", "budget_tokens": 512},
+		"tools": [{"name": "lookup", "input_schema": {"type": "object"}}],
+		"tool_choice": {"type": "any"},
+		"messages": [
+			{"role": "user", "content": [
+				{"type": "text", "text": "What is this?"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "AAAA"}}
+			]},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "Hmm", "signature": "sig"},
+				{"type": "redacted_thinking", "data": "AQID"},
+				{"type": "text", "text": "Let me check."},
+				{"type": "tool_use", "id": "toolu_1", "name": "lookup", "input": {"q": "x"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": "a cat"}
+			]}
+		]
+	}`), &req))
+
+	openAIReq, err := req.ToOpenAIRequest()
+	require.NoError(t, err)
+
+	assert.Equal(t, 1024, openAIReq.MaxTokens)
+	assert.Equal(t, []string{"END"}, openAIReq.Stop)
+	assert.Nil(t, openAIReq.Extra)
+	assert.Equal(t, map[string]interface{}{
+		"thinking": map[string]interface{}{"type": "enabled", "budget_tokens": float64(512)},
+		"top_k":    5,
+	}, openAIReq.ModelFields)
+	assert.Equal(t, &OpenAIToolChoice{Type: "required"}, openAIReq.ToolChoice)
+	require.Len(t, openAIReq.Tools, 1)
+	assert.Equal(t, "lookup", openAIReq.Tools[0].Function.Name)
+	assert.Equal(t, []OpenAIMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", ContentParts: []OpenAIContentPart{
+			{Type: "text", Text: "What is this?"},
+			{Type: "image_url", ImageURL: &OpenAIImageURL{URL: "data:image/png;base64,AAAA"}},
+		}},
+		{Role: "assistant", Content: "Let me check.", ToolCalls: []OpenAIToolCall{
+			{ID: "toolu_1", Type: "function", Function: OpenAIFunctionCall{Name: "lookup", Arguments: `{"q": "x"}`}},
+		}, Reasoning: []ReasoningBlock{{Text: "Hmm", Signature: "sig"}, {Redacted: []byte{1, 2, 3}}}},
+		{Role: "tool", ToolCallID: "toolu_1", Content: "a cat"},
+	}, openAIReq.Messages)
+
+	_, err = AnthropicRequest{Model: "claude-sonnet"}.ToOpenAIRequest()
+	assert.ErrorIs(t, err, ErrInvalidContent)
+}
+
+func TestToAnthropicResponse(t *testing.T) {
+	resp := ToAnthropicResponse(OpenAIResponse{
+		ID: "chatcmpl-123",
+		Choices: []Choice{{
+			Message: OpenAIMessage{
+				Role:      "assistant",
+				Content:   "Let me check.",
+				ToolCalls: []OpenAIToolCall{{ID: "toolu_1", Type: "function", Function: OpenAIFunctionCall{Name: "lookup", Arguments: `{"q":"x"}`}}},
+				Reasoning: []ReasoningBlock{{Text: "Hmm", Signature: "sig"}, {Redacted: []byte{1, 2, 3}}},
+			},
+			FinishReason: "tool_calls",
+		}},
+		Usage: Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, PromptTokensDetails: &PromptTokensDetails{CachedTokens: 4}},
+	}, "claude-sonnet")
+
+	bytes, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "msg_123",
+		"type": "message",
+		"role": "assistant",
+		"model": "claude-sonnet",
+		"content": [
+			{"type": "thinking", "thinking": "Hmm", "signature": "sig"},
+			{"type": "redacted_thinking", "data": "AQID"},
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "toolu_1", "name": "lookup", "input": {"q": "x"}}
+		],
+		"stop_reason": "tool_use",
+		"stop_sequence": null,
+		"usage": {"input_tokens": 6, "output_tokens": 5, "cache_creation_input_tokens": 0, "cache_read_input_tokens": 4}
+	}`, string(bytes))
+}
+
+func TestAnthropicStream(t *testing.T) {
+	stream := NewAnthropicStream("claude-sonnet")
+
+	chunk := func(delta openai.ChatCompletionChunkChoicesDelta, finishReason openai.ChatCompletionChunkChoicesFinishReason) openai.ChatCompletionChunk {
+		return openai.ChatCompletionChunk{ID: "chatcmpl-1", Choices: []openai.ChatCompletionChunkChoice{{Delta: delta, FinishReason: finishReason}}}
+	}
+
+	var events []AnthropicStreamEvent
+	for _, c := range []openai.ChatCompletionChunk{
+		chunk(openai.ChatCompletionChunkChoicesDelta{Role: "assistant"}, ""),
+		chunk(openai.ChatCompletionChunkChoicesDelta{Content: "Hi"}, ""),
+		chunk(openai.ChatCompletionChunkChoicesDelta{ToolCalls: []openai.ChatCompletionChunkChoicesDeltaToolCall{
+			{Index: 0, ID: "toolu_1", Function: openai.ChatCompletionChunkChoicesDeltaToolCallsFunction{Name: "lookup"}},
+		}}, ""),
+		chunk(openai.ChatCompletionChunkChoicesDelta{ToolCalls: []openai.ChatCompletionChunkChoicesDeltaToolCall{
+			{Index: 0, Function: openai.ChatCompletionChunkChoicesDeltaToolCallsFunction{Arguments: "{}"}},
+		}}, ""),
+		chunk(openai.ChatCompletionChunkChoicesDelta{}, "tool_calls"),
+	} {
+		events = append(events, stream.Chunk(c)...)
+	}
+	events = append(events, stream.Done(StreamIdentity{ID: "chatcmpl-1"}, Usage{PromptTokens: 2, CompletionTokens: 1})...)
+
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{
+		"message_start",
+		"content_block_start",
+		"content_block_delta",
+		"content_block_stop",
+		"content_block_start",
+		"content_block_delta",
+		"content_block_stop",
+		"message_delta",
+		"message_stop",
+	}, types)
+
+	assert.Equal(t, "msg_1", events[0].Message.ID)
+	assert.Equal(t, 1, *events[4].Index)
+	assert.Equal(t, "toolu_1", events[4].ContentBlock.ID)
+	assert.Equal(t, "{}", *events[5].Delta.PartialJSON)
+	assert.Equal(t, "tool_use", *events[7].Delta.StopReason)
+	assert.Equal(t, 1, events[7].Usage.OutputTokens)
+}
+
+func TestAnthropicStreamThinking(t *testing.T) {
+	stream := NewAnthropicStream("claude-sonnet")
+	identity := StreamIdentity{ID: "chatcmpl-1"}
+
+	events := stream.Reasoning(identity, ReasoningDelta{Text: "Hmm"})
+	events = append(events, stream.Reasoning(identity, ReasoningDelta{Text: "..."})...)
+	events = append(events, stream.Reasoning(identity, ReasoningDelta{Signature: "sig"})...)
+	events = append(events, stream.Reasoning(identity, ReasoningDelta{Redacted: []byte{1, 2, 3}})...)
+	events = append(events, stream.Chunk(openai.ChatCompletionChunk{
+		ID:      "chatcmpl-1",
+		Choices: []openai.ChatCompletionChunkChoice{{Delta: openai.ChatCompletionChunkChoicesDelta{Content: "Hi"}}},
+	})...)
+	events = append(events, stream.Done(identity, Usage{})...)
+
+	data, err := json.Marshal(events[:9])
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type": "message_start", "message": {"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet", "content": [], "stop_reason": null, "stop_sequence": null, "usage": {"input_tokens": 0, "output_tokens": 0, "cache_creation_input_tokens": 0, "cache_read_input_tokens": 0}}},
+		{"type": "content_block_start", "index": 0, "content_block": {"type": "thinking", "thinking": "", "signature": ""}},
+		{"type": "content_block_delta", "index": 0, "delta": {"type": "thinking_delta", "thinking": "Hmm"}},
+		{"type": "content_block_delta", "index": 0, "delta": {"type": "thinking_delta", "thinking": "..."}},
+		{"type": "content_block_delta", "index": 0, "delta": {"type": "signature_delta", "signature": "sig"}},
+		{"type": "content_block_stop", "index": 0},
+		{"type": "content_block_start", "index": 1, "content_block": {"type": "redacted_thinking", "data": "AQID"}},
+		{"type": "content_block_stop", "index": 1},
+		{"type": "content_block_start", "index": 2, "content_block": {"type": "text", "text": ""}}
+	]`, string(data))
+}
@@ -0,0 +1,287 @@
+package convert
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/openai/openai-go"
+)
+
+// ContentFilterResults is the Azure OpenAI content filter report for a
+// prompt or a choice. It is empty when the model has no guardrail.
+type ContentFilterResults struct {
+	Hate      *ContentFilterSeverity  `json:"hate,omitempty"`
+	SelfHarm  *ContentFilterSeverity  `json:"self_harm,omitempty"`
+	Sexual    *ContentFilterSeverity  `json:"sexual,omitempty"`
+	Violence  *ContentFilterSeverity  `json:"violence,omitempty"`
+	Profanity *ContentFilterDetection `json:"profanity,omitempty"`
+	Jailbreak *ContentFilterDetection `json:"jailbreak,omitempty"`
+}
+
+type ContentFilterSeverity struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity"`
+}
+
+type ContentFilterDetection struct {
+	Filtered bool `json:"filtered"`
+	Detected bool `json:"detected"`
+}
+
+type PromptFilterResult struct {
+	PromptIndex          int                  `json:"prompt_index"`
+	ContentFilterResults ContentFilterResults `json:"content_filter_results"`
+}
+
+var contentFilterSeverities = map[types.GuardrailContentFilterConfidence]string{
+	types.GuardrailContentFilterConfidenceNone:   "safe",
+	types.GuardrailContentFilterConfidenceLow:    "low",
+	types.GuardrailContentFilterConfidenceMedium: "medium",
+	types.GuardrailContentFilterConfidenceHigh:   "high",
+}
+
+var severityRanks = map[string]int{"safe": 0, "low": 1, "medium": 2, "high": 3}
+
+// makeContentFilterResults folds guardrail assessments into Azure's
+// categories. Bedrock has no self-harm filter, insults count as hate and
+// misconduct as violence.
+func makeContentFilterResults(assessments []types.GuardrailAssessment) ContentFilterResults {
+	results := ContentFilterResults{
+		Hate:     &ContentFilterSeverity{Severity: "safe"},
+		SelfHarm: &ContentFilterSeverity{Severity: "safe"},
+		Sexual:   &ContentFilterSeverity{Severity: "safe"},
+		Violence: &ContentFilterSeverity{Severity: "safe"},
+	}
+	for _, assessment := range assessments {
+		if assessment.ContentPolicy != nil {
+			for _, filter := range assessment.ContentPolicy.Filters {
+				blocked := filter.Action == types.GuardrailContentPolicyActionBlocked
+				var category *ContentFilterSeverity
+				switch filter.Type {
+				case types.GuardrailContentFilterTypeHate, types.GuardrailContentFilterTypeInsults:
+					category = results.Hate
+				case types.GuardrailContentFilterTypeSexual:
+					category = results.Sexual
+				case types.GuardrailContentFilterTypeViolence, types.GuardrailContentFilterTypeMisconduct:
+					category = results.Violence
+				case types.GuardrailContentFilterTypePromptAttack:
+					if results.Jailbreak == nil {
+						results.Jailbreak = &ContentFilterDetection{}
+					}
+					results.Jailbreak.Detected = true
+					results.Jailbreak.Filtered = results.Jailbreak.Filtered || blocked
+					continue
+				default:
+					continue
+				}
+				severity := contentFilterSeverities[filter.Confidence]
+				if severityRanks[severity] > severityRanks[category.Severity] {
+					category.Severity = severity
+				}
+				category.Filtered = category.Filtered || blocked
+			}
+		}
+		if assessment.WordPolicy != nil {
+			for _, word := range assessment.WordPolicy.ManagedWordLists {
+				if word.Type != types.GuardrailManagedWordTypeProfanity {
+					continue
+				}
+				if results.Profanity == nil {
+					results.Profanity = &ContentFilterDetection{}
+				}
+				results.Profanity.Detected = true
+				results.Profanity.Filtered = results.Profanity.Filtered || word.Action == types.GuardrailWordPolicyActionBlocked
+			}
+		}
+	}
+	return results
+}
+
+// PromptFilterResults reports the guardrail's assessment of the prompt.
+func PromptFilterResults(assessment *types.GuardrailTraceAssessment) []PromptFilterResult {
+	result := PromptFilterResult{}
+	if assessment != nil && len(assessment.InputAssessment) > 0 {
+		result.ContentFilterResults = makeContentFilterResults(sortedAssessments(assessment.InputAssessment))
+	}
+	return []PromptFilterResult{result}
+}
+
+// CompletionFilterResults reports the guardrail's assessment of a choice.
+func CompletionFilterResults(assessment *types.GuardrailTraceAssessment) ContentFilterResults {
+	if assessment == nil || len(assessment.OutputAssessments) == 0 {
+		return ContentFilterResults{}
+	}
+	ids := make([]string, 0, len(assessment.OutputAssessments))
+	for id := range assessment.OutputAssessments {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var assessments []types.GuardrailAssessment
+	for _, id := range ids {
+		assessments = append(assessments, assessment.OutputAssessments[id]...)
+	}
+	return makeContentFilterResults(assessments)
+}
+
+func sortedAssessments(assessments map[string]types.GuardrailAssessment) []types.GuardrailAssessment {
+	ids := make([]string, 0, len(assessments))
+	for id := range assessments {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	result := make([]types.GuardrailAssessment, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, assessments[id])
+	}
+	return result
+}
+
+type AzureChatResponse struct {
+	OpenAIResponse
+	Choices             []AzureChoice        `json:"choices"`
+	PromptFilterResults []PromptFilterResult `json:"prompt_filter_results"`
+}
+
+type AzureChoice struct {
+	Choice
+	ContentFilterResults ContentFilterResults `json:"content_filter_results"`
+}
+
+func ToAzureChatResponse(resp OpenAIResponse) AzureChatResponse {
+	result := AzureChatResponse{OpenAIResponse: resp, Choices: []AzureChoice{}}
+	var prompt *types.GuardrailTraceAssessment
+	for _, choice := range resp.Choices {
+		if prompt == nil {
+			prompt = choice.Guardrail
+		}
+		result.Choices = append(result.Choices, AzureChoice{
+			Choice:               choice,
+			ContentFilterResults: CompletionFilterResults(choice.Guardrail),
+		})
+	}
+	result.PromptFilterResults = PromptFilterResults(prompt)
+	return result
+}
+
+type AzureCompletionResponse struct {
+	OpenAICompletionResponse
+	Choices             []AzureCompletionChoice `json:"choices"`
+	PromptFilterResults []PromptFilterResult    `json:"prompt_filter_results,omitempty"`
+}
+
+type AzureCompletionChoice struct {
+	CompletionChoice
+	ContentFilterResults *ContentFilterResults `json:"content_filter_results,omitempty"`
+}
+
+func ToAzureCompletionResponse(resp OpenAIResponse, req OpenAICompletionRequest) AzureCompletionResponse {
+	completion := ToOpenAICompletionResponse(resp, req)
+	result := AzureCompletionResponse{OpenAICompletionResponse: completion, Choices: []AzureCompletionChoice{}}
+	var prompt *types.GuardrailTraceAssessment
+	for i, choice := range completion.Choices {
+		guardrail := resp.Choices[i].Guardrail
+		if prompt == nil {
+			prompt = guardrail
+		}
+		filterResults := CompletionFilterResults(guardrail)
+		result.Choices = append(result.Choices, AzureCompletionChoice{CompletionChoice: choice, ContentFilterResults: &filterResults})
+	}
+	result.PromptFilterResults = PromptFilterResults(prompt)
+	return result
+}
+
+// AzureStreamFilter collects the guardrail assessments of a stream's
+// choices, which Bedrock only reports once each choice has finished.
+type AzureStreamFilter struct {
+	assessments map[int]*types.GuardrailTraceAssessment
+}
+
+func NewAzureStreamFilter() *AzureStreamFilter {
+	return &AzureStreamFilter{assessments: map[int]*types.GuardrailTraceAssessment{}}
+}
+
+func (f *AzureStreamFilter) Add(choice int, assessment *types.GuardrailTraceAssessment) {
+	f.assessments[choice] = assessment
+}
+
+func (f *AzureStreamFilter) choices() []int {
+	choices := make([]int, 0, len(f.assessments))
+	for choice := range f.assessments {
+		choices = append(choices, choice)
+	}
+	sort.Ints(choices)
+	return choices
+}
+
+func (f *AzureStreamFilter) prompt() *types.GuardrailTraceAssessment {
+	for _, choice := range f.choices() {
+		if f.assessments[choice] != nil {
+			return f.assessments[choice]
+		}
+	}
+	return nil
+}
+
+type AzureChatChunk struct {
+	openai.ChatCompletionChunk
+	Choices             []AzureChunkChoice   `json:"choices"`
+	PromptFilterResults []PromptFilterResult `json:"prompt_filter_results,omitempty"`
+}
+
+type AzureChunkChoice struct {
+	openai.ChatCompletionChunkChoice
+	ContentFilterResults *ContentFilterResults `json:"content_filter_results,omitempty"`
+}
+
+func ToAzureChatChunk(chunk openai.ChatCompletionChunk) AzureChatChunk {
+	result := AzureChatChunk{ChatCompletionChunk: chunk, Choices: []AzureChunkChoice{}}
+	for _, choice := range chunk.Choices {
+		result.Choices = append(result.Choices, AzureChunkChoice{ChatCompletionChunkChoice: choice})
+	}
+	return result
+}
+
+// FilterChunk reports the content filter results of every choice, in the
+// way Azure's asynchronous filter does once a stream's content is out.
+func (f *AzureStreamFilter) FilterChunk(identity StreamIdentity, model string) AzureChatChunk {
+	result := AzureChatChunk{
+		ChatCompletionChunk: openai.ChatCompletionChunk{
+			ID:      identity.ID,
+			Object:  "chat.completion.chunk",
+			Created: identity.Created,
+			Model:   model,
+		},
+		Choices:             []AzureChunkChoice{},
+		PromptFilterResults: PromptFilterResults(f.prompt()),
+	}
+	for _, choice := range f.choices() {
+		filterResults := CompletionFilterResults(f.assessments[choice])
+		result.Choices = append(result.Choices, AzureChunkChoice{
+			ChatCompletionChunkChoice: openai.ChatCompletionChunkChoice{Index: int64(choice)},
+			ContentFilterResults:      &filterResults,
+		})
+	}
+	return result
+}
+
+// CompletionFilterChunk is FilterChunk for a streamed text completion.
+func (f *AzureStreamFilter) CompletionFilterChunk(identity StreamIdentity, model string) AzureCompletionResponse {
+	result := AzureCompletionResponse{
+		OpenAICompletionResponse: OpenAICompletionResponse{
+			ID:      completionID(identity.ID),
+			Object:  "text_completion",
+			Created: identity.Created,
+			Model:   model,
+		},
+		Choices:             []AzureCompletionChoice{},
+		PromptFilterResults: PromptFilterResults(f.prompt()),
+	}
+	for _, choice := range f.choices() {
+		filterResults := CompletionFilterResults(f.assessments[choice])
+		result.Choices = append(result.Choices, AzureCompletionChoice{
+			CompletionChoice:     CompletionChoice{Index: choice},
+			ContentFilterResults: &filterResults,
+		})
+	}
+	return result
+}
@@ -0,0 +1,118 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToBedrockRequestGuardrail(t *testing.T) {
+	modelMap := bedrock.ModelMap{Aliases: map[string]bedrock.ModelConfig{
+		"gpt-4o": {ModelID: "anthropic.claude", Guardrail: &bedrock.GuardrailConfig{ID: "gr-1", Version: "2"}},
+	}}
+	input := OpenAIRequest{Model: "gpt-4o", Messages: []OpenAIMessage{{Role: "user", Content: "Hello"}}}
+
+	result, err := ToBedrockRequest(context.Background(), modelMap, input)
+	require.NoError(t, err)
+	require.NotNil(t, result.GuardrailConfig)
+	assert.Equal(t, "gr-1", aws.ToString(result.GuardrailConfig.GuardrailIdentifier))
+	assert.Equal(t, "2", aws.ToString(result.GuardrailConfig.GuardrailVersion))
+	assert.Equal(t, types.GuardrailTraceEnabled, result.GuardrailConfig.Trace)
+
+	stream, err := ToBedrockStreamRequest(context.Background(), modelMap, input)
+	require.NoError(t, err)
+	require.NotNil(t, stream.GuardrailConfig)
+	assert.Equal(t, "gr-1", aws.ToString(stream.GuardrailConfig.GuardrailIdentifier))
+
+	result, err = ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+	require.NoError(t, err)
+	assert.Nil(t, result.GuardrailConfig)
+}
+
+func TestToAzureChatResponse(t *testing.T) {
+	assessment := &types.GuardrailTraceAssessment{
+		InputAssessment: map[string]types.GuardrailAssessment{
+			"gr-1": {ContentPolicy: &types.GuardrailContentPolicyAssessment{Filters: []types.GuardrailContentFilter{
+				{Type: types.GuardrailContentFilterTypeInsults, Confidence: types.GuardrailContentFilterConfidenceLow, Action: types.GuardrailContentPolicyActionNone},
+				{Type: types.GuardrailContentFilterTypePromptAttack, Confidence: types.GuardrailContentFilterConfidenceHigh, Action: types.GuardrailContentPolicyActionBlocked},
+			}}},
+		},
+		OutputAssessments: map[string][]types.GuardrailAssessment{
+			"gr-1": {{ContentPolicy: &types.GuardrailContentPolicyAssessment{Filters: []types.GuardrailContentFilter{
+				{Type: types.GuardrailContentFilterTypeViolence, Confidence: types.GuardrailContentFilterConfidenceHigh, Action: types.GuardrailContentPolicyActionBlocked},
+			}}}},
+		},
+	}
+	resp := ToAzureChatResponse(OpenAIResponse{
+		ID:      "chatcmpl-1",
+		Object:  "chat.completion",
+		Created: 1704067200,
+		Model:   "gpt-4o",
+		Choices: []Choice{{
+			Message:      OpenAIMessage{Role: "assistant", Content: "Sorry."},
+			FinishReason: "content_filter",
+			Guardrail:    assessment,
+		}},
+	})
+
+	bytes, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "chatcmpl-1",
+		"object": "chat.completion",
+		"created": 1704067200,
+		"model": "gpt-4o",
+		"choices": [{
+			"index": 0,
+			"message": {"role": "assistant", "content": "Sorry."},
+			"finish_reason": "content_filter",
+			"content_filter_results": {
+				"hate": {"filtered": false, "severity": "safe"},
+				"self_harm": {"filtered": false, "severity": "safe"},
+				"sexual": {"filtered": false, "severity": "safe"},
+				"violence": {"filtered": true, "severity": "high"}
+			}
+		}],
+		"prompt_filter_results": [{
+			"prompt_index": 0,
+			"content_filter_results": {
+				"hate": {"filtered": false, "severity": "low"},
+				"self_harm": {"filtered": false, "severity": "safe"},
+				"sexual": {"filtered": false, "severity": "safe"},
+				"violence": {"filtered": false, "severity": "safe"},
+				"jailbreak": {"filtered": true, "detected": true}
+			}
+		}],
+		"usage": {"prompt_tokens": 0, "completion_tokens": 0, "total_tokens": 0}
+	}`, string(bytes))
+
+	// Without a guardrail the results are empty.
+	resp = ToAzureChatResponse(OpenAIResponse{Choices: []Choice{{Message: OpenAIMessage{Role: "assistant"}}}})
+	assert.Equal(t, ContentFilterResults{}, resp.Choices[0].ContentFilterResults)
+	assert.Equal(t, []PromptFilterResult{{}}, resp.PromptFilterResults)
+}
+
+func TestAzureStreamFilter(t *testing.T) {
+	filter := NewAzureStreamFilter()
+	filter.Add(1, nil)
+	filter.Add(0, &types.GuardrailTraceAssessment{
+		OutputAssessments: map[string][]types.GuardrailAssessment{"gr-1": {{}}},
+	})
+
+	chunk := filter.FilterChunk(StreamIdentity{ID: "chatcmpl-1"}, "gpt-4o")
+	require.Len(t, chunk.Choices, 2)
+	assert.Equal(t, int64(0), chunk.Choices[0].Index)
+	assert.Equal(t, "safe", chunk.Choices[0].ContentFilterResults.Violence.Severity)
+	assert.Equal(t, &ContentFilterResults{}, chunk.Choices[1].ContentFilterResults)
+	assert.Len(t, chunk.PromptFilterResults, 1)
+
+	completion := filter.CompletionFilterChunk(StreamIdentity{ID: "chatcmpl-1"}, "gpt-4o")
+	assert.Equal(t, "cmpl-1", completion.ID)
+	assert.Len(t, completion.Choices, 2)
+}
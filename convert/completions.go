@@ -0,0 +1,184 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// OpenAICompletionRequest is a legacy text completion request. It is run as
+// a chat completion with the prompt as the only user turn.
+type OpenAICompletionRequest struct {
+	Model         string         `json:"model"`
+	Prompt        StringOrList   `json:"prompt"`
+	Suffix        string         `json:"suffix,omitempty"`
+	Echo          bool           `json:"echo,omitempty"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	N             int            `json:"n"`
+	Seed          int            `json:"seed,omitempty"`
+	Stop          StringOrList   `json:"stop,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	Temperature   *float64       `json:"temperature,omitempty"`
+	TopP          *float64       `json:"top_p,omitempty"`
+	Logprobs      *int           `json:"logprobs,omitempty"`
+}
+
+// StringOrList is a field that may be sent as a string or a list of strings.
+type StringOrList []string
+
+func (s *StringOrList) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		*s = StringOrList{str}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("%w: expected a string or an array of strings", ErrInvalidContent)
+	}
+	*s = list
+	return nil
+}
+
+type OpenAICompletionResponse struct {
+	ID      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model"`
+	Choices []CompletionChoice `json:"choices"`
+	Usage   *Usage             `json:"usage,omitempty"`
+}
+
+type CompletionChoice struct {
+	Text         string      `json:"text"`
+	Index        int         `json:"index"`
+	Logprobs     interface{} `json:"logprobs"`
+	FinishReason *string     `json:"finish_reason"`
+}
+
+// fillInTheMiddleInstruction asks a chat model to act like a code
+// completion model when the request has a suffix.
+const fillInTheMiddleInstruction = "Complete the text. Reply with only the text that belongs between <prefix> and <suffix>, without repeating either of them."
+
+func (r OpenAICompletionRequest) ToOpenAIRequest() (OpenAIRequest, error) {
+	if len(r.Prompt) != 1 {
+		return OpenAIRequest{}, fmt.Errorf("%w: prompt must be a single string", ErrInvalidContent)
+	}
+	if r.Logprobs != nil {
+		return OpenAIRequest{}, fmt.Errorf("%w: logprobs are not supported", ErrInvalidContent)
+	}
+
+	openAIReq := OpenAIRequest{
+		Model:         r.Model,
+		N:             r.N,
+		MaxTokens:     r.MaxTokens,
+		Seed:          r.Seed,
+		Stop:          r.Stop,
+		Stream:        r.Stream,
+		StreamOptions: r.StreamOptions,
+		Temperature:   r.Temperature,
+		TopP:          r.TopP,
+	}
+	if r.Suffix == "" {
+		openAIReq.Messages = []OpenAIMessage{{Role: "user", Content: r.Prompt[0]}}
+		return openAIReq, nil
+	}
+
+	openAIReq.Messages = []OpenAIMessage{
+		{Role: "system", Content: fillInTheMiddleInstruction},
+		{Role: "user", Content: "<prefix>" + r.Prompt[0] + "</prefix><suffix>" + r.Suffix + "</suffix>"},
+	}
+	return openAIReq, nil
+}
+
+func completionID(chatID string) string {
+	return "cmpl-" + strings.TrimPrefix(chatID, "chatcmpl-")
+}
+
+func ToOpenAICompletionResponse(resp OpenAIResponse, req OpenAICompletionRequest) OpenAICompletionResponse {
+	result := OpenAICompletionResponse{
+		ID:      completionID(resp.ID),
+		Object:  "text_completion",
+		Created: resp.Created,
+		Model:   resp.Model,
+		Usage:   &resp.Usage,
+	}
+	for _, choice := range resp.Choices {
+		text := choice.Message.Content
+		if req.Echo {
+			text = req.Prompt[0] + text
+		}
+		finishReason := choice.FinishReason
+		result.Choices = append(result.Choices, CompletionChoice{
+			Text:         text,
+			Index:        choice.Index,
+			FinishReason: &finishReason,
+		})
+	}
+	return result
+}
+
+// CompletionStream turns the chat chunks of a streamed completion into
+// text_completion chunks. With echo, each choice starts with the prompt.
+type CompletionStream struct {
+	req    OpenAICompletionRequest
+	echoed map[int64]bool
+}
+
+func NewCompletionStream(req OpenAICompletionRequest) *CompletionStream {
+	return &CompletionStream{req: req, echoed: map[int64]bool{}}
+}
+
+// Chunk converts one chunk, reporting false when it has nothing to send.
+func (s *CompletionStream) Chunk(chunk openai.ChatCompletionChunk) (OpenAICompletionResponse, bool) {
+	result := OpenAICompletionResponse{
+		ID:      completionID(chunk.ID),
+		Object:  "text_completion",
+		Created: chunk.Created,
+		Model:   chunk.Model,
+	}
+	for _, choice := range chunk.Choices {
+		text := choice.Delta.Content
+		if s.req.Echo && !s.echoed[choice.Index] {
+			s.echoed[choice.Index] = true
+			text = s.req.Prompt[0] + text
+		}
+
+		var finishReason *string
+		if choice.FinishReason != "" {
+			reason := string(choice.FinishReason)
+			finishReason = &reason
+		}
+		if text == "" && finishReason == nil {
+			continue
+		}
+		result.Choices = append(result.Choices, CompletionChoice{
+			Text:         text,
+			Index:        int(choice.Index),
+			FinishReason: finishReason,
+		})
+	}
+	return result, len(result.Choices) > 0
+}
+
+// UsageChunk builds the final, choice-less chunk sent when the client asked
+// for stream_options.include_usage.
+func (s *CompletionStream) UsageChunk(identity StreamIdentity, usage Usage) OpenAICompletionResponse {
+	return OpenAICompletionResponse{
+		ID:      completionID(identity.ID),
+		Object:  "text_completion",
+		Created: identity.Created,
+		Model:   s.req.Model,
+		Choices: []CompletionChoice{},
+		Usage:   &usage,
+	}
+}
@@ -0,0 +1,111 @@
+package convert
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAICompletionRequestToOpenAIRequest(t *testing.T) {
+	var req OpenAICompletionRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "gpt-3.5-turbo-instruct",
+		"prompt": "def add(a, b):",
+		"suffix": "\n\nprint(add(1, 2))",
+		"max_tokens": 64,
+		"stop": "\n\n",
+		"n": 2
+	}`), &req))
+
+	openAIReq, err := req.ToOpenAIRequest()
+	require.NoError(t, err)
+
+	assert.Equal(t, "gpt-3.5-turbo-instruct", openAIReq.Model)
+	assert.Equal(t, 64, openAIReq.MaxTokens)
+	assert.Equal(t, 2, openAIReq.N)
+	assert.Equal(t, []string{"\n\n"}, openAIReq.Stop)
+	assert.Equal(t, []OpenAIMessage{
+		{Role: "system", Content: fillInTheMiddleInstruction},
+		{Role: "user", Content: "<prefix>def add(a, b):</prefix><suffix>\n\nprint(add(1, 2))</suffix>"},
+	}, openAIReq.Messages)
+
+	openAIReq, err = OpenAICompletionRequest{Prompt: StringOrList{"Say hi"}}.ToOpenAIRequest()
+	require.NoError(t, err)
+	assert.Equal(t, []OpenAIMessage{{Role: "user", Content: "Say hi"}}, openAIReq.Messages)
+
+	_, err = OpenAICompletionRequest{Prompt: StringOrList{"a", "b"}}.ToOpenAIRequest()
+	assert.ErrorIs(t, err, ErrInvalidContent)
+
+	logprobs := 1
+	_, err = OpenAICompletionRequest{Prompt: StringOrList{"a"}, Logprobs: &logprobs}.ToOpenAIRequest()
+	assert.ErrorIs(t, err, ErrInvalidContent)
+}
+
+func TestToOpenAICompletionResponse(t *testing.T) {
+	resp := ToOpenAICompletionResponse(OpenAIResponse{
+		ID:      "chatcmpl-123",
+		Created: 1704067200,
+		Model:   "gpt-3.5-turbo-instruct",
+		Choices: []Choice{
+			{Index: 0, Message: OpenAIMessage{Role: "assistant", Content: " world"}, FinishReason: "stop"},
+			{Index: 1, Message: OpenAIMessage{Role: "assistant", Content: " there"}, FinishReason: "length"},
+		},
+		Usage: Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3},
+	}, OpenAICompletionRequest{Prompt: StringOrList{"Hello"}, Echo: true})
+
+	bytes, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "cmpl-123",
+		"object": "text_completion",
+		"created": 1704067200,
+		"model": "gpt-3.5-turbo-instruct",
+		"choices": [
+			{"text": "Hello world", "index": 0, "logprobs": null, "finish_reason": "stop"},
+			{"text": "Hello there", "index": 1, "logprobs": null, "finish_reason": "length"}
+		],
+		"usage": {"prompt_tokens": 1, "completion_tokens": 2, "total_tokens": 3}
+	}`, string(bytes))
+}
+
+func TestCompletionStream(t *testing.T) {
+	stream := NewCompletionStream(OpenAICompletionRequest{Model: "instruct", Prompt: StringOrList{"Hello"}, Echo: true})
+
+	chunk := func(delta openai.ChatCompletionChunkChoicesDelta, finishReason openai.ChatCompletionChunkChoicesFinishReason) openai.ChatCompletionChunk {
+		return openai.ChatCompletionChunk{
+			ID:      "chatcmpl-1",
+			Choices: []openai.ChatCompletionChunkChoice{{Delta: delta, FinishReason: finishReason}},
+		}
+	}
+
+	var texts []string
+	var finishReasons []*string
+	for _, c := range []openai.ChatCompletionChunk{
+		chunk(openai.ChatCompletionChunkChoicesDelta{Role: "assistant"}, ""),
+		chunk(openai.ChatCompletionChunkChoicesDelta{Content: " world"}, ""),
+		chunk(openai.ChatCompletionChunkChoicesDelta{}, "stop"),
+	} {
+		resp, ok := stream.Chunk(c)
+		if !ok {
+			continue
+		}
+		assert.Equal(t, "cmpl-1", resp.ID)
+		assert.Equal(t, "text_completion", resp.Object)
+		require.Len(t, resp.Choices, 1)
+		texts = append(texts, resp.Choices[0].Text)
+		finishReasons = append(finishReasons, resp.Choices[0].FinishReason)
+	}
+
+	// The role chunk carries the echoed prompt.
+	assert.Equal(t, []string{"Hello", " world", ""}, texts)
+	assert.Nil(t, finishReasons[0])
+	require.NotNil(t, finishReasons[2])
+	assert.Equal(t, "stop", *finishReasons[2])
+
+	usage := stream.UsageChunk(StreamIdentity{ID: "chatcmpl-1"}, Usage{TotalTokens: 3})
+	assert.Empty(t, usage.Choices)
+	assert.Equal(t, 3, usage.Usage.TotalTokens)
+}
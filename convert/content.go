@@ -0,0 +1,261 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+type OpenAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *OpenAIImageURL `json:"image_url,omitempty"`
+	File     *OpenAIFile     `json:"file,omitempty"`
+}
+
+type OpenAIImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+var ErrInvalidContent = errors.New("invalid message content")
+
+// Bedrock rejects images larger than 3.75 MB.
+const maxImageBytes = 3_932_160
+
+// ImageFetcher downloads a remote image and returns its bytes and MIME type.
+type ImageFetcher func(ctx context.Context, url string) ([]byte, string, error)
+
+var imageFetcher ImageFetcher
+
+// SetImageFetcher enables downloading of http(s) image URLs. Passing nil
+// disables it again, which is the default.
+func SetImageFetcher(fetcher ImageFetcher) ImageFetcher {
+	old := imageFetcher
+	imageFetcher = fetcher
+	return old
+}
+
+var ErrForbiddenAddress = errors.New("forbidden address")
+
+// NewHTTPImageFetcher downloads images from public addresses only, so that
+// clients cannot reach the sidecar's network, such as the instance metadata
+// service. Proxies are not used, as the address checked would be the proxy's.
+func NewHTTPImageFetcher(timeout time.Duration) ImageFetcher {
+	dialer := &net.Dialer{Timeout: timeout, Control: rejectPrivateAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	client := &http.Client{Timeout: timeout, Transport: transport}
+
+	return func(ctx context.Context, url string) ([]byte, string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: invalid image URL", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: failed to fetch image", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, "", fmt.Errorf("failed to fetch image: %s", resp.Status)
+		}
+
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: failed to read image", err)
+		}
+		return data, resp.Header.Get("Content-Type"), nil
+	}
+}
+
+// rejectPrivateAddress is a net.Dialer Control hook, which runs after name
+// resolution and so also covers redirects and DNS names of private hosts.
+func rejectPrivateAddress(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrForbiddenAddress, err)
+	}
+	addr := addrPort.Addr().Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || sharedAddressSpace.Contains(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which is as internal as
+// the private ranges.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func (m *OpenAIMessage) UnmarshalJSON(data []byte) error {
+	type alias OpenAIMessage
+	var raw struct {
+		alias
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*m = OpenAIMessage(raw.alias)
+	content := bytes.TrimSpace(raw.Content)
+	switch {
+	case len(content) == 0 || bytes.Equal(content, []byte("null")):
+	case content[0] == '"':
+		return json.Unmarshal(content, &m.Content)
+	case content[0] == '[':
+		return json.Unmarshal(content, &m.ContentParts)
+	default:
+		return fmt.Errorf("%w: content must be a string or an array", ErrInvalidContent)
+	}
+	return nil
+}
+
+func (m OpenAIMessage) MarshalJSON() ([]byte, error) {
+	type alias OpenAIMessage
+	if m.ContentParts == nil {
+		return json.Marshal(alias(m))
+	}
+	return json.Marshal(struct {
+		alias
+		Content []OpenAIContentPart `json:"content"`
+	}{alias(m), m.ContentParts})
+}
+
+// contentText returns the text of the message, joining the text parts of
+// an array-form content.
+func (m OpenAIMessage) contentText() string {
+	if m.ContentParts == nil {
+		return m.Content
+	}
+	texts := make([]string, 0, len(m.ContentParts))
+	for _, part := range m.ContentParts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func makeContentBlocks(ctx context.Context, msg OpenAIMessage) ([]types.ContentBlock, error) {
+	if msg.ContentParts == nil {
+		return []types.ContentBlock{&types.ContentBlockMemberText{Value: msg.Content}}, nil
+	}
+
+	blocks := make([]types.ContentBlock, 0, len(msg.ContentParts))
+	for _, part := range msg.ContentParts {
+		switch part.Type {
+		case "text":
+			blocks = append(blocks, &types.ContentBlockMemberText{Value: part.Text})
+		case "image_url":
+			if part.ImageURL == nil {
+				return nil, fmt.Errorf("%w: image_url part is missing image_url", ErrInvalidContent)
+			}
+			image, err := makeImageBlock(ctx, part.ImageURL.URL)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, image)
+		case "file":
+			document, err := makeDocumentBlock(part.File)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, document)
+		default:
+			return nil, fmt.Errorf("%w: unsupported content part type %q", ErrInvalidContent, part.Type)
+		}
+	}
+	return blocks, nil
+}
+
+func makeImageBlock(ctx context.Context, url string) (types.ContentBlock, error) {
+	var data []byte
+	var mimeType string
+
+	switch {
+	case strings.HasPrefix(url, "data:"):
+		var err error
+		mimeType, data, err = decodeDataURL(url)
+		if err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(url, "http://"), strings.HasPrefix(url, "https://"):
+		if imageFetcher == nil {
+			return nil, fmt.Errorf("%w: remote image URLs are not supported, send the image as a base64 data URL", ErrInvalidContent)
+		}
+		var err error
+		data, mimeType, err = imageFetcher(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidContent, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported image URL", ErrInvalidContent)
+	}
+
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("%w: image exceeds %d bytes", ErrInvalidContent, maxImageBytes)
+	}
+
+	format, err := imageFormat(mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ContentBlockMemberImage{
+		Value: types.ImageBlock{
+			Format: format,
+			Source: &types.ImageSourceMemberBytes{Value: data},
+		},
+	}, nil
+}
+
+// decodeDataURL parses a base64 data URL of the form
+// data:<mime type>;base64,<data>.
+func decodeDataURL(url string) (string, []byte, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !ok {
+		return "", nil, fmt.Errorf("%w: malformed data URL", ErrInvalidContent)
+	}
+
+	mimeType, isBase64 := strings.CutSuffix(header, ";base64")
+	if !isBase64 {
+		return "", nil, fmt.Errorf("%w: data URL must be base64 encoded", ErrInvalidContent)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: data URL is not valid base64", ErrInvalidContent)
+	}
+	return mimeType, data, nil
+}
+
+func imageFormat(mimeType string) (types.ImageFormat, error) {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	switch strings.ToLower(strings.TrimSpace(mimeType)) {
+	case "image/png":
+		return types.ImageFormatPng, nil
+	case "image/jpeg", "image/jpg":
+		return types.ImageFormatJpeg, nil
+	case "image/gif":
+		return types.ImageFormatGif, nil
+	case "image/webp":
+		return types.ImageFormatWebp, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported image type %q", ErrInvalidContent, mimeType)
+	}
+}
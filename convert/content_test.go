@@ -0,0 +1,141 @@
+package convert
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIMessageUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected OpenAIMessage
+	}{
+		{
+			name:     "string content",
+			input:    `{"role":"user","content":"Hello"}`,
+			expected: OpenAIMessage{Role: "user", Content: "Hello"},
+		},
+		{
+			name:     "null content",
+			input:    `{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"f","arguments":"{}"}}]}`,
+			expected: OpenAIMessage{Role: "assistant", ToolCalls: []OpenAIToolCall{{ID: "call_1", Type: "function", Function: OpenAIFunctionCall{Name: "f", Arguments: "{}"}}}},
+		},
+		{
+			name:  "array content",
+			input: `{"role":"user","content":[{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}}]}`,
+			expected: OpenAIMessage{Role: "user", ContentParts: []OpenAIContentPart{
+				{Type: "text", Text: "What is this?"},
+				{Type: "image_url", ImageURL: &OpenAIImageURL{URL: "data:image/png;base64,AAAA"}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msg OpenAIMessage
+			require.NoError(t, json.Unmarshal([]byte(tt.input), &msg))
+			assert.Equal(t, tt.expected, msg)
+		})
+	}
+}
+
+func TestToBedrockRequestImages(t *testing.T) {
+	image := []byte("\x89PNG fake image")
+	input := OpenAIRequest{
+		Model: "anthropic.claude-v2",
+		Messages: []OpenAIMessage{
+			{Role: "system", ContentParts: []OpenAIContentPart{{Type: "text", Text: "Be brief"}}},
+			{Role: "user", ContentParts: []OpenAIContentPart{
+				{Type: "text", Text: "What is this?"},
+				{Type: "image_url", ImageURL: &OpenAIImageURL{URL: "data:image/png;base64," + base64.StdEncoding.EncodeToString(image)}},
+			}},
+		},
+	}
+
+	result, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+	require.NoError(t, err)
+
+	require.Len(t, result.System, 1)
+	assert.Equal(t, "Be brief", result.System[0].(*types.SystemContentBlockMemberText).Value)
+
+	require.Len(t, result.Messages, 1)
+	require.Len(t, result.Messages[0].Content, 2)
+	assert.Equal(t, "What is this?", result.Messages[0].Content[0].(*types.ContentBlockMemberText).Value)
+	imageBlock := result.Messages[0].Content[1].(*types.ContentBlockMemberImage).Value
+	assert.Equal(t, types.ImageFormatPng, imageBlock.Format)
+	assert.Equal(t, image, imageBlock.Source.(*types.ImageSourceMemberBytes).Value)
+}
+
+func TestToBedrockRequestImageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "remote URL", url: "https://example.com/cat.png"},
+		{name: "unsupported type", url: "data:image/tiff;base64,AAAA"},
+		{name: "not base64", url: "data:image/png,AAAA"},
+		{name: "invalid base64", url: "data:image/png;base64,!!!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := OpenAIRequest{
+				Messages: []OpenAIMessage{
+					{Role: "user", ContentParts: []OpenAIContentPart{
+						{Type: "image_url", ImageURL: &OpenAIImageURL{URL: tt.url}},
+					}},
+				},
+			}
+			_, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+			assert.ErrorIs(t, err, ErrInvalidContent)
+		})
+	}
+}
+
+func TestSetImageFetcher(t *testing.T) {
+	oldFetcher := SetImageFetcher(func(_ context.Context, url string) ([]byte, string, error) {
+		if url == "https://example.com/missing.png" {
+			return nil, "", errors.New("404 Not Found")
+		}
+		return []byte("jpeg"), "image/jpeg", nil
+	})
+	defer SetImageFetcher(oldFetcher)
+
+	block, err := makeImageBlock(context.Background(), "https://example.com/cat.jpg")
+	require.NoError(t, err)
+	imageBlock := block.(*types.ContentBlockMemberImage).Value
+	assert.Equal(t, types.ImageFormatJpeg, imageBlock.Format)
+	assert.Equal(t, []byte("jpeg"), imageBlock.Source.(*types.ImageSourceMemberBytes).Value)
+
+	_, err = makeImageBlock(context.Background(), "https://example.com/missing.png")
+	assert.ErrorIs(t, err, ErrInvalidContent)
+}
+
+func TestHTTPImageFetcherRejectsPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("png"))
+	}))
+	defer server.Close()
+
+	fetch := NewHTTPImageFetcher(time.Second)
+	_, _, err := fetch(context.Background(), server.URL+"/cat.png")
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+
+	for _, address := range []string{"10.0.0.1:80", "169.254.169.254:80", "[fd00:ec2::254]:80", "[::ffff:127.0.0.1]:443", "100.64.0.1:80"} {
+		assert.ErrorIs(t, rejectPrivateAddress("tcp", address, nil), ErrForbiddenAddress, address)
+	}
+	assert.NoError(t, rejectPrivateAddress("tcp", "93.184.216.34:443", nil))
+}
@@ -0,0 +1,147 @@
+package convert
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+type OpenAIFile struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+}
+
+// Bedrock accepts at most 5 documents of up to 4.5 MB each per request.
+const (
+	maxDocuments     = 5
+	maxDocumentBytes = 4_718_592
+)
+
+var documentFormats = map[string]types.DocumentFormat{
+	"application/pdf":    types.DocumentFormatPdf,
+	"text/csv":           types.DocumentFormatCsv,
+	"application/msword": types.DocumentFormatDoc,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": types.DocumentFormatDocx,
+	"application/vnd.ms-excel": types.DocumentFormatXls,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": types.DocumentFormatXlsx,
+	"text/html":     types.DocumentFormatHtml,
+	"text/plain":    types.DocumentFormatTxt,
+	"text/markdown": types.DocumentFormatMd,
+}
+
+var (
+	invalidDocumentNameChars = regexp.MustCompile(`[^A-Za-z0-9\s\-()\[\]]+`)
+	repeatedWhitespace       = regexp.MustCompile(`\s+`)
+)
+
+func makeDocumentBlock(file *OpenAIFile) (types.ContentBlock, error) {
+	if file == nil {
+		return nil, fmt.Errorf("%w: file part is missing file", ErrInvalidContent)
+	}
+	if file.FileID != "" {
+		return nil, fmt.Errorf("%w: file_id is not supported, send the file as file_data", ErrInvalidContent)
+	}
+	if file.FileData == "" {
+		return nil, fmt.Errorf("%w: file part is missing file_data", ErrInvalidContent)
+	}
+
+	var mimeType string
+	var data []byte
+	if strings.HasPrefix(file.FileData, "data:") {
+		var err error
+		mimeType, data, err = decodeDataURL(file.FileData)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		data, err = base64.StdEncoding.DecodeString(file.FileData)
+		if err != nil {
+			return nil, fmt.Errorf("%w: file_data is not valid base64", ErrInvalidContent)
+		}
+	}
+
+	if len(data) > maxDocumentBytes {
+		return nil, fmt.Errorf("%w: document %q exceeds %d bytes", ErrInvalidContent, file.Filename, maxDocumentBytes)
+	}
+
+	format, err := documentFormat(mimeType, file.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ContentBlockMemberDocument{
+		Value: types.DocumentBlock{
+			Format: format,
+			Name:   aws.String(sanitizeDocumentName(file.Filename)),
+			Source: &types.DocumentSourceMemberBytes{Value: data},
+		},
+	}, nil
+}
+
+// documentFormat picks the format from the MIME type, falling back to the
+// file extension when the MIME type is missing or generic.
+func documentFormat(mimeType, filename string) (types.DocumentFormat, error) {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	if format, ok := documentFormats[strings.ToLower(strings.TrimSpace(mimeType))]; ok {
+		return format, nil
+	}
+
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(filename), "."))
+	if ext == "htm" {
+		ext = "html"
+	}
+	for _, format := range types.DocumentFormat("").Values() {
+		if string(format) == ext {
+			return format, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: unsupported document type %q", ErrInvalidContent, filename)
+}
+
+// sanitizeDocumentName reduces a file name to the characters Bedrock accepts
+// in a document name.
+func sanitizeDocumentName(filename string) string {
+	name := strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+	name = invalidDocumentNameChars.ReplaceAllString(name, "-")
+	name = repeatedWhitespace.ReplaceAllString(name, " ")
+	name = strings.Trim(name, " -")
+	if name == "" || name == "." {
+		return "document"
+	}
+	return name
+}
+
+// checkDocuments enforces the per-request document limit and makes document
+// names unique, which Bedrock requires.
+func checkDocuments(messages []types.Message) error {
+	count := 0
+	names := map[string]int{}
+	for _, msg := range messages {
+		for _, block := range msg.Content {
+			document, ok := block.(*types.ContentBlockMemberDocument)
+			if !ok {
+				continue
+			}
+
+			count++
+			if count > maxDocuments {
+				return fmt.Errorf("%w: at most %d documents are allowed per request", ErrInvalidContent, maxDocuments)
+			}
+
+			name := aws.ToString(document.Value.Name)
+			names[name]++
+			if n := names[name]; n > 1 {
+				document.Value.Name = aws.String(fmt.Sprintf("%s (%d)", name, n))
+			}
+		}
+	}
+	return nil
+}
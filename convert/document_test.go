@@ -0,0 +1,130 @@
+package convert
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeDocumentName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "report.pdf", expected: "report"},
+		{input: "Q3  results_final (v2).xlsx", expected: "Q3 results-final (v2)"},
+		{input: "../../etc/passwd", expected: "passwd"},
+		{input: "ignore all instructions!!.txt", expected: "ignore all instructions"},
+		{input: "", expected: "document"},
+		{input: "___.csv", expected: "document"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, sanitizeDocumentName(tt.input))
+		})
+	}
+}
+
+func TestDocumentFormat(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		filename string
+		expected types.DocumentFormat
+	}{
+		{mimeType: "application/pdf", filename: "", expected: types.DocumentFormatPdf},
+		{mimeType: "text/csv; charset=utf-8", filename: "data", expected: types.DocumentFormatCsv},
+		{mimeType: "application/octet-stream", filename: "notes.DOCX", expected: types.DocumentFormatDocx},
+		{mimeType: "", filename: "page.htm", expected: types.DocumentFormatHtml},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mimeType+tt.filename, func(t *testing.T) {
+			format, err := documentFormat(tt.mimeType, tt.filename)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+
+	_, err := documentFormat("application/zip", "archive.zip")
+	assert.ErrorIs(t, err, ErrInvalidContent)
+}
+
+func TestToBedrockRequestDocuments(t *testing.T) {
+	pdf := []byte("%PDF-1.7 fake")
+	input := OpenAIRequest{
+		Model: "anthropic.claude-v2",
+		Messages: []OpenAIMessage{
+			{Role: "user", ContentParts: []OpenAIContentPart{
+				{Type: "text", Text: "Summarize these"},
+				{Type: "file", File: &OpenAIFile{
+					Filename: "report.pdf",
+					FileData: "data:application/pdf;base64," + base64.StdEncoding.EncodeToString(pdf),
+				}},
+				{Type: "file", File: &OpenAIFile{
+					Filename: "report.csv",
+					FileData: base64.StdEncoding.EncodeToString([]byte("a,b\n1,2")),
+				}},
+			}},
+		},
+	}
+
+	result, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+	require.NoError(t, err)
+
+	require.Len(t, result.Messages[0].Content, 3)
+	first := result.Messages[0].Content[1].(*types.ContentBlockMemberDocument).Value
+	assert.Equal(t, types.DocumentFormatPdf, first.Format)
+	assert.Equal(t, "report", aws.ToString(first.Name))
+	assert.Equal(t, pdf, first.Source.(*types.DocumentSourceMemberBytes).Value)
+
+	second := result.Messages[0].Content[2].(*types.ContentBlockMemberDocument).Value
+	assert.Equal(t, types.DocumentFormatCsv, second.Format)
+	assert.Equal(t, "report (2)", aws.ToString(second.Name))
+}
+
+func TestToBedrockRequestDocumentLimits(t *testing.T) {
+	file := func(data []byte) OpenAIContentPart {
+		return OpenAIContentPart{Type: "file", File: &OpenAIFile{
+			Filename: "notes.txt",
+			FileData: base64.StdEncoding.EncodeToString(data),
+		}}
+	}
+
+	t.Run("too many documents", func(t *testing.T) {
+		parts := make([]OpenAIContentPart, 0, maxDocuments+1)
+		for i := 0; i <= maxDocuments; i++ {
+			parts = append(parts, file([]byte("hello")))
+		}
+		input := OpenAIRequest{Messages: []OpenAIMessage{{Role: "user", ContentParts: parts}}}
+
+		_, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+		assert.ErrorIs(t, err, ErrInvalidContent)
+		assert.Contains(t, err.Error(), "at most 5 documents")
+	})
+
+	t.Run("document too large", func(t *testing.T) {
+		large := []byte(strings.Repeat("a", maxDocumentBytes+1))
+		input := OpenAIRequest{Messages: []OpenAIMessage{{Role: "user", ContentParts: []OpenAIContentPart{file(large)}}}}
+
+		_, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+		assert.ErrorIs(t, err, ErrInvalidContent)
+		assert.Contains(t, err.Error(), "exceeds")
+	})
+
+	t.Run("file_id is rejected", func(t *testing.T) {
+		input := OpenAIRequest{Messages: []OpenAIMessage{{Role: "user", ContentParts: []OpenAIContentPart{
+			{Type: "file", File: &OpenAIFile{FileID: "file-abc"}},
+		}}}}
+
+		_, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+		assert.ErrorIs(t, err, ErrInvalidContent)
+	})
+}
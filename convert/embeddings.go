@@ -0,0 +1,287 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+type OpenAIEmbeddingRequest struct {
+	Model          string         `json:"model"`
+	Input          EmbeddingInput `json:"input"`
+	Dimensions     int            `json:"dimensions,omitempty"`
+	EncodingFormat string         `json:"encoding_format,omitempty"`
+	User           string         `json:"user,omitempty"`
+	// InputType is passed to Cohere models, which embed search queries and
+	// documents differently. It defaults to search_document.
+	InputType string `json:"input_type,omitempty"`
+}
+
+// EmbeddingInput is a single string or a list of strings. Token arrays are
+// not supported, since Bedrock models do not share OpenAI's tokenizer.
+type EmbeddingInput []string
+
+func (i *EmbeddingInput) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*i = EmbeddingInput{s}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("%w: input must be a string or an array of strings", ErrInvalidContent)
+	}
+	*i = list
+	return nil
+}
+
+type OpenAIEmbeddingResponse struct {
+	Object string            `json:"object"`
+	Data   []OpenAIEmbedding `json:"data"`
+	Model  string            `json:"model"`
+	Usage  EmbeddingUsage    `json:"usage"`
+}
+
+type OpenAIEmbedding struct {
+	Object string `json:"object"`
+	Index  int    `json:"index"`
+	// Embedding is a []float64, or a base64 string of little-endian
+	// float32s when encoding_format is base64.
+	Embedding interface{} `json:"embedding"`
+}
+
+type EmbeddingUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// EmbeddingOutput is the result of one InvokeModel call. InputTokens is the
+// count from the response headers, 0 if Bedrock did not send one.
+type EmbeddingOutput struct {
+	Body        []byte
+	InputTokens int
+}
+
+// embeddingFormat is the request and response body of a family of
+// embedding models.
+type embeddingFormat interface {
+	// batchSize is the number of texts one call can embed.
+	batchSize() int
+	makeBody(texts []string, req OpenAIEmbeddingRequest) ([]byte, error)
+	// parseBody returns the embeddings and the input token count, if the
+	// body has one.
+	parseBody(body []byte) ([][]float64, int, error)
+}
+
+func makeEmbeddingFormat(modelID string) (embeddingFormat, error) {
+	provider := bedrock.ModelProvider(modelID)
+	switch {
+	case provider == "amazon" && strings.Contains(modelID, "titan-embed-text-v2"):
+		return titanEmbeddingFormat{dimensions: true}, nil
+	case provider == "amazon" && (strings.Contains(modelID, "titan-embed-text-v1") || strings.Contains(modelID, "titan-embed-g1-text")):
+		return titanEmbeddingFormat{}, nil
+	case provider == "cohere" && strings.Contains(modelID, "embed-v4"):
+		return cohereEmbeddingFormat{dimensions: true}, nil
+	case provider == "cohere" && strings.Contains(modelID, "embed-"):
+		return cohereEmbeddingFormat{}, nil
+	default:
+		return nil, fmt.Errorf("%w: model %q is not a supported embedding model", ErrInvalidContent, modelID)
+	}
+}
+
+// ToBedrockEmbeddingRequests splits the input into as many InvokeModel calls
+// as the model needs.
+func ToBedrockEmbeddingRequests(modelMap bedrock.ModelMap, req OpenAIEmbeddingRequest) ([]bedrockruntime.InvokeModelInput, error) {
+	if len(req.Input) == 0 {
+		return nil, fmt.Errorf("%w: input must not be empty", ErrInvalidContent)
+	}
+	for _, text := range req.Input {
+		if text == "" {
+			return nil, fmt.Errorf("%w: input must not contain empty strings", ErrInvalidContent)
+		}
+	}
+	switch req.EncodingFormat {
+	case "", "float", "base64":
+	default:
+		return nil, fmt.Errorf("%w: encoding_format must be float or base64", ErrInvalidContent)
+	}
+
+	modelConfig, err := modelMap.Resolve(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	modelID := modelConfig.ModelID
+	format, err := makeEmbeddingFormat(modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []bedrockruntime.InvokeModelInput
+	for start := 0; start < len(req.Input); start += format.batchSize() {
+		end := min(start+format.batchSize(), len(req.Input))
+		body, err := format.makeBody(req.Input[start:end], req)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, bedrockruntime.InvokeModelInput{
+			ModelId:     aws.String(modelID),
+			Body:        body,
+			ContentType: aws.String("application/json"),
+			Accept:      aws.String("application/json"),
+		})
+	}
+	return inputs, nil
+}
+
+// ToOpenAIEmbeddingResponse assembles the outputs of the calls made by
+// ToBedrockEmbeddingRequests, in the same order.
+func ToOpenAIEmbeddingResponse(modelMap bedrock.ModelMap, req OpenAIEmbeddingRequest, outputs []EmbeddingOutput) (OpenAIEmbeddingResponse, error) {
+	format, err := makeEmbeddingFormat(modelMap.BedrockModelID(req.Model))
+	if err != nil {
+		return OpenAIEmbeddingResponse{}, err
+	}
+
+	resp := OpenAIEmbeddingResponse{
+		Object: "list",
+		Data:   []OpenAIEmbedding{},
+		Model:  req.Model,
+	}
+	for _, output := range outputs {
+		embeddings, tokens, err := format.parseBody(output.Body)
+		if err != nil {
+			return OpenAIEmbeddingResponse{}, err
+		}
+		if output.InputTokens > 0 {
+			tokens = output.InputTokens
+		}
+		resp.Usage.PromptTokens += tokens
+
+		for _, embedding := range embeddings {
+			data := OpenAIEmbedding{Object: "embedding", Index: len(resp.Data), Embedding: embedding}
+			if req.EncodingFormat == "base64" {
+				data.Embedding = encodeEmbedding(embedding)
+			}
+			resp.Data = append(resp.Data, data)
+		}
+	}
+	if len(resp.Data) != len(req.Input) {
+		return OpenAIEmbeddingResponse{}, fmt.Errorf("expected %d embeddings, got %d", len(req.Input), len(resp.Data))
+	}
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens
+	return resp, nil
+}
+
+func encodeEmbedding(embedding []float64) string {
+	buf := make([]byte, 4*len(embedding))
+	for i, value := range embedding {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(value)))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// titanEmbeddingFormat embeds one text per call. Only v2 accepts dimensions.
+type titanEmbeddingFormat struct {
+	dimensions bool
+}
+
+func (f titanEmbeddingFormat) batchSize() int {
+	return 1
+}
+
+func (f titanEmbeddingFormat) makeBody(texts []string, req OpenAIEmbeddingRequest) ([]byte, error) {
+	body := map[string]interface{}{"inputText": texts[0]}
+	if req.Dimensions != 0 {
+		if !f.dimensions {
+			return nil, fmt.Errorf("%w: model does not support dimensions", ErrInvalidContent)
+		}
+		switch req.Dimensions {
+		case 256, 512, 1024:
+		default:
+			return nil, fmt.Errorf("%w: dimensions must be 256, 512 or 1024", ErrInvalidContent)
+		}
+		body["dimensions"] = req.Dimensions
+	}
+	if f.dimensions {
+		// OpenAI embeddings are normalized, so cosine and dot product agree.
+		body["normalize"] = true
+	}
+	return json.Marshal(body)
+}
+
+func (f titanEmbeddingFormat) parseBody(body []byte) ([][]float64, int, error) {
+	var resp struct {
+		Embedding           []float64 `json:"embedding"`
+		InputTextTokenCount int       `json:"inputTextTokenCount"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, 0, fmt.Errorf("%w: failed to decode Titan embedding", err)
+	}
+	return [][]float64{resp.Embedding}, resp.InputTextTokenCount, nil
+}
+
+// cohereEmbeddingFormat embeds up to 96 texts per call. Only Embed v4
+// accepts dimensions.
+type cohereEmbeddingFormat struct {
+	dimensions bool
+}
+
+func (f cohereEmbeddingFormat) batchSize() int {
+	return 96
+}
+
+func (f cohereEmbeddingFormat) makeBody(texts []string, req OpenAIEmbeddingRequest) ([]byte, error) {
+	inputType := req.InputType
+	if inputType == "" {
+		inputType = "search_document"
+	}
+	body := map[string]interface{}{
+		"texts":           texts,
+		"input_type":      inputType,
+		"embedding_types": []string{"float"},
+	}
+	if req.Dimensions != 0 {
+		if !f.dimensions {
+			return nil, fmt.Errorf("%w: model does not support dimensions", ErrInvalidContent)
+		}
+		body["output_dimension"] = req.Dimensions
+	}
+	return json.Marshal(body)
+}
+
+func (f cohereEmbeddingFormat) parseBody(body []byte) ([][]float64, int, error) {
+	var resp struct {
+		Embeddings json.RawMessage `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, 0, fmt.Errorf("%w: failed to decode Cohere embeddings", err)
+	}
+
+	// With embedding_types the embeddings are keyed by type, otherwise
+	// they are a plain list.
+	var embeddings [][]float64
+	if trimmed := bytes.TrimSpace(resp.Embeddings); len(trimmed) > 0 && trimmed[0] == '{' {
+		var byType struct {
+			Float [][]float64 `json:"float"`
+		}
+		if err := json.Unmarshal(trimmed, &byType); err != nil {
+			return nil, 0, fmt.Errorf("%w: failed to decode Cohere embeddings", err)
+		}
+		embeddings = byType.Float
+	} else if err := json.Unmarshal(trimmed, &embeddings); err != nil {
+		return nil, 0, fmt.Errorf("%w: failed to decode Cohere embeddings", err)
+	}
+	return embeddings, 0, nil
+}
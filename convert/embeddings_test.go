@@ -0,0 +1,160 @@
+package convert
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddingInputUnmarshal(t *testing.T) {
+	var req OpenAIEmbeddingRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"model": "m", "input": "hello"}`), &req))
+	assert.Equal(t, EmbeddingInput{"hello"}, req.Input)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"model": "m", "input": ["a", "b"]}`), &req))
+	assert.Equal(t, EmbeddingInput{"a", "b"}, req.Input)
+
+	err := json.Unmarshal([]byte(`{"model": "m", "input": [1, 2, 3]}`), &req)
+	assert.ErrorIs(t, err, ErrInvalidContent)
+}
+
+func TestToBedrockEmbeddingRequests(t *testing.T) {
+	modelMap := bedrock.ModelMap{Aliases: map[string]bedrock.ModelConfig{
+		"text-embedding-3-small": {ModelID: "amazon.titan-embed-text-v2:0"},
+		"embed-english":          {ModelID: "cohere.embed-english-v3"},
+	}}
+
+	tests := []struct {
+		name          string
+		req           OpenAIEmbeddingRequest
+		expectedModel string
+		expectedBody  []string
+		expectedError bool
+	}{
+		{
+			name:          "Titan v2 embeds one text per call",
+			req:           OpenAIEmbeddingRequest{Model: "text-embedding-3-small", Input: EmbeddingInput{"a", "b"}, Dimensions: 512},
+			expectedModel: "amazon.titan-embed-text-v2:0",
+			expectedBody: []string{
+				`{"inputText": "a", "dimensions": 512, "normalize": true}`,
+				`{"inputText": "b", "dimensions": 512, "normalize": true}`,
+			},
+		},
+		{
+			name:          "Titan v1 has no dimensions",
+			req:           OpenAIEmbeddingRequest{Model: "amazon.titan-embed-text-v1", Input: EmbeddingInput{"a"}, Dimensions: 512},
+			expectedError: true,
+		},
+		{
+			name:          "Titan v2 rejects other dimensions",
+			req:           OpenAIEmbeddingRequest{Model: "text-embedding-3-small", Input: EmbeddingInput{"a"}, Dimensions: 1536},
+			expectedError: true,
+		},
+		{
+			name:          "Cohere batches texts",
+			req:           OpenAIEmbeddingRequest{Model: "embed-english", Input: EmbeddingInput{"a", "b"}, InputType: "search_query"},
+			expectedModel: "cohere.embed-english-v3",
+			expectedBody: []string{
+				`{"texts": ["a", "b"], "input_type": "search_query", "embedding_types": ["float"]}`,
+			},
+		},
+		{
+			name:          "Cohere v4 dimensions",
+			req:           OpenAIEmbeddingRequest{Model: "us.cohere.embed-v4:0", Input: EmbeddingInput{"a"}, Dimensions: 256},
+			expectedModel: "us.cohere.embed-v4:0",
+			expectedBody: []string{
+				`{"texts": ["a"], "input_type": "search_document", "embedding_types": ["float"], "output_dimension": 256}`,
+			},
+		},
+		{
+			name:          "Chat model",
+			req:           OpenAIEmbeddingRequest{Model: "anthropic.claude-v2", Input: EmbeddingInput{"a"}},
+			expectedError: true,
+		},
+		{
+			name:          "Empty input",
+			req:           OpenAIEmbeddingRequest{Model: "text-embedding-3-small", Input: EmbeddingInput{""}},
+			expectedError: true,
+		},
+		{
+			name:          "Unknown encoding format",
+			req:           OpenAIEmbeddingRequest{Model: "text-embedding-3-small", Input: EmbeddingInput{"a"}, EncodingFormat: "int8"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs, err := ToBedrockEmbeddingRequests(modelMap, tt.req)
+			if tt.expectedError {
+				assert.ErrorIs(t, err, ErrInvalidContent)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, inputs, len(tt.expectedBody))
+			for i, input := range inputs {
+				assert.Equal(t, tt.expectedModel, aws.ToString(input.ModelId))
+				assert.JSONEq(t, tt.expectedBody[i], string(input.Body))
+			}
+		})
+	}
+}
+
+func TestCohereEmbeddingBatchSize(t *testing.T) {
+	input := make(EmbeddingInput, 100)
+	for i := range input {
+		input[i] = "text"
+	}
+	inputs, err := ToBedrockEmbeddingRequests(bedrock.ModelMap{}, OpenAIEmbeddingRequest{Model: "cohere.embed-english-v3", Input: input})
+	require.NoError(t, err)
+	assert.Len(t, inputs, 2)
+}
+
+func TestToOpenAIEmbeddingResponse(t *testing.T) {
+	titan := OpenAIEmbeddingRequest{Model: "amazon.titan-embed-text-v2:0", Input: EmbeddingInput{"a", "b"}}
+	resp, err := ToOpenAIEmbeddingResponse(bedrock.ModelMap{}, titan, []EmbeddingOutput{
+		{Body: []byte(`{"embedding": [0.1, 0.2], "inputTextTokenCount": 3}`)},
+		{Body: []byte(`{"embedding": [0.3, 0.4], "inputTextTokenCount": 4}`), InputTokens: 5},
+	})
+	require.NoError(t, err)
+
+	bytes, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"object": "list",
+		"data": [
+			{"object": "embedding", "index": 0, "embedding": [0.1, 0.2]},
+			{"object": "embedding", "index": 1, "embedding": [0.3, 0.4]}
+		],
+		"model": "amazon.titan-embed-text-v2:0",
+		"usage": {"prompt_tokens": 8, "total_tokens": 8}
+	}`, string(bytes))
+
+	cohere := OpenAIEmbeddingRequest{Model: "cohere.embed-english-v3", Input: EmbeddingInput{"a", "b"}, EncodingFormat: "base64"}
+	resp, err = ToOpenAIEmbeddingResponse(bedrock.ModelMap{}, cohere, []EmbeddingOutput{
+		{Body: []byte(`{"embeddings": {"float": [[0.5, -1], [2, 0.25]]}}`), InputTokens: 2},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, 2, resp.Usage.TotalTokens)
+
+	encoded, ok := resp.Data[1].Embedding.(string)
+	require.True(t, ok)
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	require.Len(t, raw, 8)
+	assert.Equal(t, float32(2), math.Float32frombits(binary.LittleEndian.Uint32(raw[0:])))
+	assert.Equal(t, float32(0.25), math.Float32frombits(binary.LittleEndian.Uint32(raw[4:])))
+
+	_, err = ToOpenAIEmbeddingResponse(bedrock.ModelMap{}, cohere, []EmbeddingOutput{
+		{Body: []byte(`{"embeddings": [[0.5, -1]]}`)},
+	})
+	assert.Error(t, err, "fewer embeddings than inputs")
+}
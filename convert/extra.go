@@ -0,0 +1,112 @@
+package convert
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sort"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+)
+
+// openAIRequestFields are the documented chat completion request fields.
+// Anything else is treated as a provider-specific parameter.
+var openAIRequestFields = map[string]bool{
+	"audio":                 true,
+	"extra_body":            true,
+	"frequency_penalty":     true,
+	"function_call":         true,
+	"functions":             true,
+	"logit_bias":            true,
+	"logprobs":              true,
+	"max_completion_tokens": true,
+	"max_tokens":            true,
+	"messages":              true,
+	"metadata":              true,
+	"modalities":            true,
+	"model":                 true,
+	"n":                     true,
+	"parallel_tool_calls":   true,
+	"prediction":            true,
+	"presence_penalty":      true,
+	"reasoning_effort":      true,
+	"response_format":       true,
+	"seed":                  true,
+	"service_tier":          true,
+	"stop":                  true,
+	"store":                 true,
+	"stream":                true,
+	"stream_options":        true,
+	"temperature":           true,
+	"tool_choice":           true,
+	"tools":                 true,
+	"top_logprobs":          true,
+	"top_p":                 true,
+	"user":                  true,
+	"web_search_options":    true,
+}
+
+// UnmarshalJSON collects unknown top-level fields and the fields of an
+// explicit extra_body object into Extra.
+func (r *OpenAIRequest) UnmarshalJSON(data []byte) error {
+	type alias OpenAIRequest
+	if err := json.Unmarshal(data, (*alias)(r)); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var extra map[string]interface{}
+	if extraBody, ok := fields["extra_body"]; ok {
+		if err := json.Unmarshal(extraBody, &extra); err != nil {
+			return err
+		}
+	}
+	for key, value := range fields {
+		if openAIRequestFields[key] {
+			continue
+		}
+		if extra == nil {
+			extra = map[string]interface{}{}
+		}
+		if _, ok := extra[key]; ok {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(value, &v); err != nil {
+			return err
+		}
+		extra[key] = v
+	}
+	r.Extra = extra
+
+	return nil
+}
+
+func makeAdditionalModelRequestFields(config bedrock.ModelConfig, openAIReq OpenAIRequest) document.Interface {
+	allowed := map[string]interface{}{}
+	if len(openAIReq.Extra) > 0 {
+		var dropped []string
+		allowed, dropped = config.FilterPassthrough(openAIReq.Extra)
+		if len(dropped) > 0 {
+			sort.Strings(dropped)
+			slog.Debug("Dropped request fields not allowed for model", "model", openAIReq.Model, "fields", dropped)
+		}
+	}
+	for key, value := range openAIReq.ModelFields {
+		allowed[key] = value
+	}
+	for key := range allowed {
+		if config.Inference.Strips(key) {
+			delete(allowed, key)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	return document.NewLazyDocument(allowed)
+}
@@ -0,0 +1,88 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIRequestUnmarshalExtra(t *testing.T) {
+	var req OpenAIRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "claude",
+		"messages": [{"role": "user", "content": "Hello"}],
+		"temperature": 0.5,
+		"user": "alice",
+		"top_k": 50,
+		"extra_body": {"top_k": 10, "thinking": {"type": "enabled", "budget_tokens": 1024}}
+	}`), &req))
+
+	assert.Equal(t, "claude", req.Model)
+	assert.Equal(t, 0.5, *req.Temperature)
+	assert.Equal(t, map[string]interface{}{
+		"top_k": float64(10),
+		"thinking": map[string]interface{}{
+			"type":          "enabled",
+			"budget_tokens": float64(1024),
+		},
+	}, req.Extra)
+}
+
+func TestToBedrockRequestAdditionalModelRequestFields(t *testing.T) {
+	modelMap := bedrock.ModelMap{Aliases: map[string]bedrock.ModelConfig{
+		"claude": {ModelID: "anthropic.claude-v2", Passthrough: []string{"top_k"}},
+		"any":    {ModelID: "mistral.mistral-large", Passthrough: []string{"*"}},
+	}}
+	extra := map[string]interface{}{"top_k": 50, "safe_prompt": true}
+
+	t.Run("allowlisted fields are forwarded", func(t *testing.T) {
+		input := OpenAIRequest{Model: "claude", Extra: extra, Messages: []OpenAIMessage{{Role: "user", Content: "Hello"}}}
+
+		result, err := ToBedrockRequest(context.Background(), modelMap, input)
+		require.NoError(t, err)
+
+		require.NotNil(t, result.AdditionalModelRequestFields)
+		data, err := result.AdditionalModelRequestFields.MarshalSmithyDocument()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"top_k":50}`, string(data))
+	})
+
+	t.Run("wildcard forwards everything", func(t *testing.T) {
+		input := OpenAIRequest{Model: "any", Extra: extra, Messages: []OpenAIMessage{{Role: "user", Content: "Hello"}}}
+
+		result, err := ToBedrockStreamRequest(context.Background(), modelMap, input)
+		require.NoError(t, err)
+
+		data, err := result.AdditionalModelRequestFields.MarshalSmithyDocument()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"top_k":50,"safe_prompt":true}`, string(data))
+	})
+
+	t.Run("nothing is forwarded without an allowlist", func(t *testing.T) {
+		input := OpenAIRequest{Model: "unmapped", Extra: extra, Messages: []OpenAIMessage{{Role: "user", Content: "Hello"}}}
+
+		result, err := ToBedrockRequest(context.Background(), modelMap, input)
+		require.NoError(t, err)
+		assert.Nil(t, result.AdditionalModelRequestFields)
+	})
+
+	t.Run("thinking is always forwarded", func(t *testing.T) {
+		input := OpenAIRequest{
+			Model:       "unmapped",
+			Extra:       extra,
+			ModelFields: map[string]interface{}{"thinking": map[string]interface{}{"type": "enabled", "budget_tokens": 1024}},
+			Messages:    []OpenAIMessage{{Role: "user", Content: "Hello"}},
+		}
+
+		result, err := ToBedrockRequest(context.Background(), modelMap, input)
+		require.NoError(t, err)
+
+		data, err := result.AdditionalModelRequestFields.MarshalSmithyDocument()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"thinking":{"type":"enabled","budget_tokens":1024}}`, string(data))
+	})
+}
@@ -0,0 +1,64 @@
+package convert
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// leadingUserPlaceholder is inserted before a conversation that starts with
+// an assistant turn, since Bedrock requires the first message to be a user's.
+const leadingUserPlaceholder = "."
+
+// normalizeMessages reshapes an OpenAI conversation into one Bedrock
+// accepts: no empty text, alternating roles and a leading user turn.
+func normalizeMessages(messages []types.Message) []types.Message {
+	normalized := make([]types.Message, 0, len(messages))
+
+	for i, msg := range messages {
+		content := dropEmptyText(msg.Content)
+		if len(content) < len(msg.Content) {
+			slog.Debug("Normalized conversation: dropped empty text content", "message", i, "role", msg.Role)
+		}
+		if len(content) == 0 {
+			slog.Debug("Normalized conversation: dropped empty message", "message", i, "role", msg.Role)
+			continue
+		}
+
+		if len(normalized) > 0 && normalized[len(normalized)-1].Role == msg.Role {
+			slog.Debug("Normalized conversation: merged consecutive messages", "message", i, "role", msg.Role)
+			last := &normalized[len(normalized)-1]
+			last.Content = append(last.Content, content...)
+			continue
+		}
+
+		normalized = append(normalized, types.Message{
+			Role:    msg.Role,
+			Content: content,
+		})
+	}
+
+	if len(normalized) > 0 && normalized[0].Role == types.ConversationRoleAssistant {
+		slog.Debug("Normalized conversation: inserted user message before leading assistant message")
+		normalized = append([]types.Message{{
+			Role: types.ConversationRoleUser,
+			Content: []types.ContentBlock{
+				&types.ContentBlockMemberText{Value: leadingUserPlaceholder},
+			},
+		}}, normalized...)
+	}
+
+	return normalized
+}
+
+func dropEmptyText(content []types.ContentBlock) []types.ContentBlock {
+	kept := make([]types.ContentBlock, 0, len(content))
+	for _, block := range content {
+		if text, ok := block.(*types.ContentBlockMemberText); ok && strings.TrimSpace(text.Value) == "" {
+			continue
+		}
+		kept = append(kept, block)
+	}
+	return kept
+}
@@ -0,0 +1,108 @@
+package convert
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func textOf(t *testing.T, block types.ContentBlock) string {
+	t.Helper()
+	text, ok := block.(*types.ContentBlockMemberText)
+	require.True(t, ok, "expected a text block, got %T", block)
+	return text.Value
+}
+
+func TestNormalizeMessages(t *testing.T) {
+	t.Run("merges consecutive same-role messages", func(t *testing.T) {
+		input := OpenAIRequest{Messages: []OpenAIMessage{
+			{Role: "user", Content: "Hello"},
+			{Role: "user", Content: "Are you there?"},
+			{Role: "assistant", Content: "Yes"},
+		}}
+
+		result, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+		require.NoError(t, err)
+
+		require.Len(t, result.Messages, 2)
+		assert.Equal(t, types.ConversationRoleUser, result.Messages[0].Role)
+		require.Len(t, result.Messages[0].Content, 2)
+		assert.Equal(t, "Hello", textOf(t, result.Messages[0].Content[0]))
+		assert.Equal(t, "Are you there?", textOf(t, result.Messages[0].Content[1]))
+	})
+
+	t.Run("drops empty content and messages", func(t *testing.T) {
+		input := OpenAIRequest{Messages: []OpenAIMessage{
+			{Role: "user", Content: "Hello"},
+			{Role: "assistant", Content: "  "},
+			{Role: "user", ContentParts: []OpenAIContentPart{
+				{Type: "text", Text: ""},
+				{Type: "text", Text: "Anyone?"},
+			}},
+		}}
+
+		result, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+		require.NoError(t, err)
+
+		require.Len(t, result.Messages, 1)
+		require.Len(t, result.Messages[0].Content, 2)
+		assert.Equal(t, "Hello", textOf(t, result.Messages[0].Content[0]))
+		assert.Equal(t, "Anyone?", textOf(t, result.Messages[0].Content[1]))
+	})
+
+	t.Run("inserts a user turn before a leading assistant message", func(t *testing.T) {
+		input := OpenAIRequest{Messages: []OpenAIMessage{
+			{Role: "system", Content: "Be brief"},
+			{Role: "assistant", Content: "How can I help?"},
+			{Role: "user", Content: "Hello"},
+		}}
+
+		result, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+		require.NoError(t, err)
+
+		require.Len(t, result.Messages, 3)
+		assert.Equal(t, types.ConversationRoleUser, result.Messages[0].Role)
+		assert.Equal(t, leadingUserPlaceholder, textOf(t, result.Messages[0].Content[0]))
+		assert.Equal(t, types.ConversationRoleAssistant, result.Messages[1].Role)
+	})
+
+	t.Run("maps developer to system and drops empty system messages", func(t *testing.T) {
+		input := OpenAIRequest{Messages: []OpenAIMessage{
+			{Role: "developer", Content: "Be brief"},
+			{Role: "system", Content: ""},
+			{Role: "user", Content: "Hello"},
+		}}
+
+		result, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+		require.NoError(t, err)
+
+		require.Len(t, result.System, 1)
+		assert.Equal(t, "Be brief", result.System[0].(*types.SystemContentBlockMemberText).Value)
+		require.Len(t, result.Messages, 1)
+	})
+
+	t.Run("keeps tool calls of an assistant message without text", func(t *testing.T) {
+		input := OpenAIRequest{Messages: []OpenAIMessage{
+			{Role: "user", Content: "Weather?"},
+			{Role: "assistant", ToolCalls: []OpenAIToolCall{
+				{ID: "call_1", Type: "function", Function: OpenAIFunctionCall{Name: "get_weather", Arguments: "{}"}},
+			}},
+			{Role: "tool", ToolCallID: "call_1", Content: "sunny"},
+			{Role: "user", Content: "Thanks"},
+		}}
+
+		result, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+		require.NoError(t, err)
+
+		require.Len(t, result.Messages, 3)
+		require.Len(t, result.Messages[1].Content, 1)
+		assert.IsType(t, &types.ContentBlockMemberToolUse{}, result.Messages[1].Content[0])
+		require.Len(t, result.Messages[2].Content, 2)
+		assert.IsType(t, &types.ContentBlockMemberToolResult{}, result.Messages[2].Content[0])
+		assert.Equal(t, "Thanks", textOf(t, result.Messages[2].Content[1]))
+	})
+}
@@ -0,0 +1,407 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// Ollama requests are translated into OpenAIRequests so that they share the
+// Bedrock conversion, and responses are translated back from the OpenAI
+// shape.
+
+type OllamaChatRequest struct {
+	Model     string                 `json:"model"`
+	Messages  []OllamaMessage        `json:"messages"`
+	Tools     []OpenAITool           `json:"tools,omitempty"`
+	Format    json.RawMessage        `json:"format,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+	Stream    *bool                  `json:"stream,omitempty"`
+	KeepAlive json.RawMessage        `json:"keep_alive,omitempty"`
+}
+
+type OllamaGenerateRequest struct {
+	Model     string                 `json:"model"`
+	Prompt    string                 `json:"prompt"`
+	Suffix    string                 `json:"suffix,omitempty"`
+	System    string                 `json:"system,omitempty"`
+	Images    []string               `json:"images,omitempty"`
+	Format    json.RawMessage        `json:"format,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+	Stream    *bool                  `json:"stream,omitempty"`
+	Raw       bool                   `json:"raw,omitempty"`
+	KeepAlive json.RawMessage        `json:"keep_alive,omitempty"`
+}
+
+type OllamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []OllamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type OllamaToolCall struct {
+	Function OllamaFunctionCall `json:"function"`
+}
+
+type OllamaFunctionCall struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+type OllamaChatResponse struct {
+	Model      string        `json:"model"`
+	CreatedAt  time.Time     `json:"created_at"`
+	Message    OllamaMessage `json:"message"`
+	Done       bool          `json:"done"`
+	DoneReason string        `json:"done_reason,omitempty"`
+	OllamaMetrics
+}
+
+type OllamaGenerateResponse struct {
+	Model      string    `json:"model"`
+	CreatedAt  time.Time `json:"created_at"`
+	Response   string    `json:"response"`
+	Done       bool      `json:"done"`
+	DoneReason string    `json:"done_reason,omitempty"`
+	OllamaMetrics
+}
+
+// OllamaMetrics are only sent with the final response. Durations are in
+// nanoseconds; Bedrock does not report load or evaluation times.
+type OllamaMetrics struct {
+	TotalDuration   int64 `json:"total_duration,omitempty"`
+	PromptEvalCount int   `json:"prompt_eval_count,omitempty"`
+	EvalCount       int   `json:"eval_count,omitempty"`
+}
+
+// Streaming reports whether the response should be streamed, which Ollama
+// does unless told otherwise.
+func (r OllamaChatRequest) Streaming() bool {
+	return r.Stream == nil || *r.Stream
+}
+
+func (r OllamaGenerateRequest) Streaming() bool {
+	return r.Stream == nil || *r.Stream
+}
+
+func (r OllamaChatRequest) ToOpenAIRequest() (OpenAIRequest, error) {
+	openAIReq := OpenAIRequest{
+		Model:  r.Model,
+		Tools:  r.Tools,
+		Stream: r.Streaming(),
+	}
+
+	// Ollama tool calls carry no ids, so they are numbered here and tool
+	// results are matched to them in order.
+	var pending []string
+	nextID := 0
+	for _, msg := range r.Messages {
+		openAIMsg := OpenAIMessage{Role: msg.Role, Content: msg.Content}
+		if len(msg.Images) > 0 {
+			parts, err := makeOllamaContentParts(msg.Content, msg.Images)
+			if err != nil {
+				return OpenAIRequest{}, err
+			}
+			openAIMsg.Content = ""
+			openAIMsg.ContentParts = parts
+		}
+
+		switch msg.Role {
+		case "assistant":
+			for _, call := range msg.ToolCalls {
+				arguments, err := json.Marshal(call.Function.Arguments)
+				if err != nil {
+					return OpenAIRequest{}, fmt.Errorf("%w: %w", ErrInvalidToolCall, err)
+				}
+				id := fmt.Sprintf("call_%d", nextID)
+				nextID++
+				pending = append(pending, id)
+				openAIMsg.ToolCalls = append(openAIMsg.ToolCalls, OpenAIToolCall{
+					ID:       id,
+					Type:     "function",
+					Function: OpenAIFunctionCall{Name: call.Function.Name, Arguments: string(arguments)},
+				})
+			}
+		case "tool":
+			if len(pending) == 0 {
+				return OpenAIRequest{}, fmt.Errorf("%w: tool message does not follow a tool call", ErrInvalidToolCall)
+			}
+			openAIMsg.ToolCallID = pending[0]
+			pending = pending[1:]
+		}
+		openAIReq.Messages = append(openAIReq.Messages, openAIMsg)
+	}
+
+	if err := applyOllamaOptions(&openAIReq, r.Options, r.Format); err != nil {
+		return OpenAIRequest{}, err
+	}
+	return openAIReq, nil
+}
+
+func (r OllamaGenerateRequest) ToOpenAIRequest() (OpenAIRequest, error) {
+	if r.Suffix != "" {
+		return OpenAIRequest{}, fmt.Errorf("%w: suffix is not supported", ErrInvalidContent)
+	}
+
+	openAIReq := OpenAIRequest{
+		Model:  r.Model,
+		Stream: r.Streaming(),
+	}
+	if r.System != "" {
+		openAIReq.Messages = append(openAIReq.Messages, OpenAIMessage{Role: "system", Content: r.System})
+	}
+	prompt := OpenAIMessage{Role: "user", Content: r.Prompt}
+	if len(r.Images) > 0 {
+		parts, err := makeOllamaContentParts(r.Prompt, r.Images)
+		if err != nil {
+			return OpenAIRequest{}, err
+		}
+		prompt.Content = ""
+		prompt.ContentParts = parts
+	}
+	openAIReq.Messages = append(openAIReq.Messages, prompt)
+
+	if err := applyOllamaOptions(&openAIReq, r.Options, r.Format); err != nil {
+		return OpenAIRequest{}, err
+	}
+	return openAIReq, nil
+}
+
+// makeOllamaContentParts turns Ollama's bare base64 images into data URLs,
+// sniffing the image type since Ollama does not send one.
+func makeOllamaContentParts(text string, images []string) ([]OpenAIContentPart, error) {
+	var parts []OpenAIContentPart
+	if text != "" {
+		parts = append(parts, OpenAIContentPart{Type: "text", Text: text})
+	}
+	for _, image := range images {
+		data, err := base64.StdEncoding.DecodeString(image)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid base64 image", ErrInvalidContent)
+		}
+		mimeType := http.DetectContentType(data)
+		parts = append(parts, OpenAIContentPart{
+			Type:     "image_url",
+			ImageURL: &OpenAIImageURL{URL: "data:" + mimeType + ";base64," + image},
+		})
+	}
+	return parts, nil
+}
+
+// applyOllamaOptions maps the Ollama options that have an OpenAI
+// counterpart. The rest are offered to the model as provider-specific
+// fields, subject to the model's passthrough list.
+func applyOllamaOptions(openAIReq *OpenAIRequest, options map[string]interface{}, format json.RawMessage) error {
+	for _, name := range sortedKeys(options) {
+		value := options[name]
+		switch name {
+		case "num_predict":
+			n, ok := value.(float64)
+			if !ok {
+				return fmt.Errorf("%w: options.num_predict must be a number", ErrInvalidContent)
+			}
+			// A negative num_predict means no limit.
+			if n > 0 {
+				openAIReq.MaxTokens = int(n)
+			}
+		case "temperature":
+			t, ok := value.(float64)
+			if !ok {
+				return fmt.Errorf("%w: options.temperature must be a number", ErrInvalidContent)
+			}
+			openAIReq.Temperature = &t
+		case "top_p":
+			p, ok := value.(float64)
+			if !ok {
+				return fmt.Errorf("%w: options.top_p must be a number", ErrInvalidContent)
+			}
+			openAIReq.TopP = &p
+		case "seed":
+			seed, ok := value.(float64)
+			if !ok {
+				return fmt.Errorf("%w: options.seed must be a number", ErrInvalidContent)
+			}
+			openAIReq.Seed = int(seed)
+		case "stop":
+			stop, err := ollamaStop(value)
+			if err != nil {
+				return err
+			}
+			openAIReq.Stop = stop
+		default:
+			if openAIReq.Extra == nil {
+				openAIReq.Extra = map[string]interface{}{}
+			}
+			openAIReq.Extra[name] = value
+		}
+	}
+
+	format = bytes.TrimSpace(format)
+	switch {
+	case len(format) == 0 || bytes.Equal(format, []byte("null")) || bytes.Equal(format, []byte(`""`)):
+	case bytes.Equal(format, []byte(`"json"`)):
+		openAIReq.ResponseFormat = &OpenAIResponseFormat{Type: "json_object"}
+	case format[0] == '{':
+		var schema map[string]interface{}
+		if err := json.Unmarshal(format, &schema); err != nil {
+			return fmt.Errorf("%w: invalid format schema", ErrInvalidContent)
+		}
+		openAIReq.ResponseFormat = &OpenAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &OpenAIJSONSchema{Name: "response", Schema: schema},
+		}
+	default:
+		return fmt.Errorf("%w: format must be \"json\" or a JSON schema", ErrInvalidContent)
+	}
+	return nil
+}
+
+func ollamaStop(value interface{}) ([]string, error) {
+	switch stop := value.(type) {
+	case string:
+		return []string{stop}, nil
+	case []interface{}:
+		result := make([]string, 0, len(stop))
+		for _, s := range stop {
+			str, ok := s.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: options.stop must be a list of strings", ErrInvalidContent)
+			}
+			result = append(result, str)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("%w: options.stop must be a list of strings", ErrInvalidContent)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func ToOllamaChatResponse(resp OpenAIResponse) OllamaChatResponse {
+	result := OllamaChatResponse{
+		Model:     resp.Model,
+		CreatedAt: timeProvider().UTC(),
+		Message:   OllamaMessage{Role: "assistant"},
+		Done:      true,
+		OllamaMetrics: OllamaMetrics{
+			PromptEvalCount: resp.Usage.PromptTokens,
+			EvalCount:       resp.Usage.CompletionTokens,
+		},
+	}
+	if len(resp.Choices) == 0 {
+		result.DoneReason = "stop"
+		return result
+	}
+
+	choice := resp.Choices[0]
+	result.Message.Content = choice.Message.Content
+	result.DoneReason = ollamaDoneReason(choice.FinishReason)
+	for _, call := range choice.Message.ToolCalls {
+		result.Message.ToolCalls = append(result.Message.ToolCalls, toOllamaToolCall(call.Function.Name, call.Function.Arguments))
+	}
+	return result
+}
+
+// ToGenerate reshapes a chat response for /api/generate.
+func (r OllamaChatResponse) ToGenerate() OllamaGenerateResponse {
+	return OllamaGenerateResponse{
+		Model:         r.Model,
+		CreatedAt:     r.CreatedAt,
+		Response:      r.Message.Content,
+		Done:          r.Done,
+		DoneReason:    r.DoneReason,
+		OllamaMetrics: r.OllamaMetrics,
+	}
+}
+
+func ollamaDoneReason(finishReason string) string {
+	if finishReason == "length" {
+		return "length"
+	}
+	return "stop"
+}
+
+func toOllamaToolCall(name, arguments string) OllamaToolCall {
+	args := map[string]interface{}{}
+	if strings.TrimSpace(arguments) != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			args = map[string]interface{}{}
+		}
+	}
+	return OllamaToolCall{Function: OllamaFunctionCall{Name: name, Arguments: args}}
+}
+
+// OllamaStream turns the OpenAI chunks of one stream into Ollama responses.
+// Ollama sends tool calls whole, so their argument fragments are collected
+// and sent with the final response.
+type OllamaStream struct {
+	model        string
+	toolCalls    []OpenAIToolCall
+	finishReason string
+}
+
+func NewOllamaStream(model string) *OllamaStream {
+	return &OllamaStream{model: model}
+}
+
+// Chunk converts one chunk, reporting false when it carries no content.
+func (s *OllamaStream) Chunk(chunk openai.ChatCompletionChunk) (OllamaChatResponse, bool) {
+	var content string
+	for _, choice := range chunk.Choices {
+		content += choice.Delta.Content
+		for _, call := range choice.Delta.ToolCalls {
+			for int64(len(s.toolCalls)) <= call.Index {
+				s.toolCalls = append(s.toolCalls, OpenAIToolCall{})
+			}
+			s.toolCalls[call.Index].Function.Name += call.Function.Name
+			s.toolCalls[call.Index].Function.Arguments += call.Function.Arguments
+		}
+		if choice.FinishReason != "" {
+			s.finishReason = string(choice.FinishReason)
+		}
+	}
+	if content == "" {
+		return OllamaChatResponse{}, false
+	}
+
+	return OllamaChatResponse{
+		Model:     s.model,
+		CreatedAt: timeProvider().UTC(),
+		Message:   OllamaMessage{Role: "assistant", Content: content},
+	}, true
+}
+
+// Done builds the final response of the stream.
+func (s *OllamaStream) Done(usage Usage) OllamaChatResponse {
+	result := OllamaChatResponse{
+		Model:      s.model,
+		CreatedAt:  timeProvider().UTC(),
+		Message:    OllamaMessage{Role: "assistant"},
+		Done:       true,
+		DoneReason: ollamaDoneReason(s.finishReason),
+		OllamaMetrics: OllamaMetrics{
+			PromptEvalCount: usage.PromptTokens,
+			EvalCount:       usage.CompletionTokens,
+		},
+	}
+	for _, call := range s.toolCalls {
+		result.Message.ToolCalls = append(result.Message.ToolCalls, toOllamaToolCall(call.Function.Name, call.Function.Arguments))
+	}
+	return result
+}
@@ -0,0 +1,212 @@
+package convert
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOllamaChatRequestToOpenAIRequest(t *testing.T) {
+	var ollamaReq OllamaChatRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "llama3",
+		"messages": [
+			{"role": "system", "content": "Be brief."},
+			{"role": "user", "content": "Weather in Paris?"},
+			{"role": "assistant", "content": "", "tool_calls": [
+				{"function": {"name": "get_weather", "arguments": {"city": "Paris"}}}
+			]},
+			{"role": "tool", "content": "Sunny"}
+		],
+		"options": {"num_predict": 128, "temperature": 0.2, "top_p": 0.9, "stop": ["END"], "top_k": 40},
+		"format": "json",
+		"keep_alive": "5m"
+	}`), &ollamaReq))
+
+	assert.True(t, ollamaReq.Streaming())
+
+	openAIReq, err := ollamaReq.ToOpenAIRequest()
+	require.NoError(t, err)
+
+	assert.Equal(t, "llama3", openAIReq.Model)
+	assert.True(t, openAIReq.Stream)
+	assert.Equal(t, 128, openAIReq.MaxTokens)
+	assert.Equal(t, aws.Float64(0.2), openAIReq.Temperature)
+	assert.Equal(t, aws.Float64(0.9), openAIReq.TopP)
+	assert.Equal(t, []string{"END"}, openAIReq.Stop)
+	assert.Equal(t, map[string]interface{}{"top_k": float64(40)}, openAIReq.Extra)
+	assert.Equal(t, &OpenAIResponseFormat{Type: "json_object"}, openAIReq.ResponseFormat)
+
+	require.Len(t, openAIReq.Messages, 4)
+	require.Len(t, openAIReq.Messages[2].ToolCalls, 1)
+	call := openAIReq.Messages[2].ToolCalls[0]
+	assert.Equal(t, "get_weather", call.Function.Name)
+	assert.JSONEq(t, `{"city": "Paris"}`, call.Function.Arguments)
+	assert.Equal(t, call.ID, openAIReq.Messages[3].ToolCallID)
+}
+
+func TestOllamaRequestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  OllamaChatRequest
+	}{
+		{
+			name: "Tool result without a tool call",
+			req:  OllamaChatRequest{Messages: []OllamaMessage{{Role: "tool", Content: "Sunny"}}},
+		},
+		{
+			name: "Invalid image",
+			req:  OllamaChatRequest{Messages: []OllamaMessage{{Role: "user", Images: []string{"not base64!"}}}},
+		},
+		{
+			name: "Invalid option type",
+			req:  OllamaChatRequest{Options: map[string]interface{}{"temperature": "hot"}},
+		},
+		{
+			name: "Invalid format",
+			req:  OllamaChatRequest{Format: json.RawMessage(`"xml"`)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.req.ToOpenAIRequest()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestOllamaGenerateRequestToOpenAIRequest(t *testing.T) {
+	png := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\nrest"))
+	stream := false
+	ollamaReq := OllamaGenerateRequest{
+		Model:  "llava",
+		Prompt: "Describe this",
+		System: "You are a vision model.",
+		Images: []string{png},
+		Format: json.RawMessage(`{"type": "object", "properties": {"caption": {"type": "string"}}}`),
+		Options: map[string]interface{}{
+			"num_predict": float64(-1),
+			"stop":        "\n",
+		},
+		Stream: &stream,
+	}
+
+	openAIReq, err := ollamaReq.ToOpenAIRequest()
+	require.NoError(t, err)
+
+	assert.False(t, openAIReq.Stream)
+	assert.Zero(t, openAIReq.MaxTokens)
+	assert.Equal(t, []string{"\n"}, openAIReq.Stop)
+	require.NotNil(t, openAIReq.ResponseFormat)
+	assert.Equal(t, "json_schema", openAIReq.ResponseFormat.Type)
+	assert.Equal(t, "object", openAIReq.ResponseFormat.JSONSchema.Schema["type"])
+
+	require.Len(t, openAIReq.Messages, 2)
+	assert.Equal(t, "system", openAIReq.Messages[0].Role)
+	assert.Equal(t, []OpenAIContentPart{
+		{Type: "text", Text: "Describe this"},
+		{Type: "image_url", ImageURL: &OpenAIImageURL{URL: "data:image/png;base64," + png}},
+	}, openAIReq.Messages[1].ContentParts)
+
+	_, err = OllamaGenerateRequest{Prompt: "def f(", Suffix: "return x"}.ToOpenAIRequest()
+	assert.ErrorIs(t, err, ErrInvalidContent)
+}
+
+func TestToOllamaChatResponse(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	oldProvider := SetTimeProvider(func() time.Time {
+		return fixedTime
+	})
+	defer SetTimeProvider(oldProvider)
+
+	resp := ToOllamaChatResponse(OpenAIResponse{
+		Model: "llama3",
+		Choices: []Choice{{
+			Message: OpenAIMessage{
+				Role:    "assistant",
+				Content: "Let me check.",
+				ToolCalls: []OpenAIToolCall{{
+					ID:       "tooluse_1",
+					Type:     "function",
+					Function: OpenAIFunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+				}},
+			},
+			FinishReason: "tool_calls",
+		}},
+		Usage: Usage{PromptTokens: 12, CompletionTokens: 7, TotalTokens: 19},
+	})
+
+	bytes, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"model": "llama3",
+		"created_at": "2024-01-01T00:00:00Z",
+		"message": {
+			"role": "assistant",
+			"content": "Let me check.",
+			"tool_calls": [{"function": {"name": "get_weather", "arguments": {"city": "Paris"}}}]
+		},
+		"done": true,
+		"done_reason": "stop",
+		"prompt_eval_count": 12,
+		"eval_count": 7
+	}`, string(bytes))
+
+	bytes, err = json.Marshal(resp.ToGenerate())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"model": "llama3",
+		"created_at": "2024-01-01T00:00:00Z",
+		"response": "Let me check.",
+		"done": true,
+		"done_reason": "stop",
+		"prompt_eval_count": 12,
+		"eval_count": 7
+	}`, string(bytes))
+}
+
+func TestOllamaStream(t *testing.T) {
+	stream := NewOllamaStream("llama3")
+
+	chunks := []openai.ChatCompletionChunk{
+		{Choices: []openai.ChatCompletionChunkChoice{{Delta: openai.ChatCompletionChunkChoicesDelta{Role: "assistant"}}}},
+		{Choices: []openai.ChatCompletionChunkChoice{{Delta: openai.ChatCompletionChunkChoicesDelta{Content: "Hello"}}}},
+		{Choices: []openai.ChatCompletionChunkChoice{{Delta: openai.ChatCompletionChunkChoicesDelta{
+			ToolCalls: []openai.ChatCompletionChunkChoicesDeltaToolCall{{Index: 0, Function: openai.ChatCompletionChunkChoicesDeltaToolCallsFunction{Name: "get_weather"}}},
+		}}}},
+		{Choices: []openai.ChatCompletionChunkChoice{{Delta: openai.ChatCompletionChunkChoicesDelta{
+			ToolCalls: []openai.ChatCompletionChunkChoicesDeltaToolCall{{Index: 0, Function: openai.ChatCompletionChunkChoicesDeltaToolCallsFunction{Arguments: `{"city":`}}},
+		}}}},
+		{Choices: []openai.ChatCompletionChunkChoice{{Delta: openai.ChatCompletionChunkChoicesDelta{
+			ToolCalls: []openai.ChatCompletionChunkChoicesDeltaToolCall{{Index: 0, Function: openai.ChatCompletionChunkChoicesDeltaToolCallsFunction{Arguments: `"Paris"}`}}},
+		}}}},
+		{Choices: []openai.ChatCompletionChunkChoice{{FinishReason: "length"}}},
+	}
+
+	var contents []string
+	for _, chunk := range chunks {
+		resp, ok := stream.Chunk(chunk)
+		if ok {
+			assert.False(t, resp.Done)
+			contents = append(contents, resp.Message.Content)
+		}
+	}
+	assert.Equal(t, []string{"Hello"}, contents)
+
+	final := stream.Done(Usage{PromptTokens: 3, CompletionTokens: 4})
+	assert.True(t, final.Done)
+	assert.Equal(t, "length", final.DoneReason)
+	assert.Equal(t, 3, final.PromptEvalCount)
+	assert.Equal(t, 4, final.EvalCount)
+	assert.Equal(t, []OllamaToolCall{{Function: OllamaFunctionCall{
+		Name:      "get_weather",
+		Arguments: map[string]interface{}{"city": "Paris"},
+	}}}, final.Message.ToolCalls)
+}
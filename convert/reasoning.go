@@ -0,0 +1,79 @@
+package convert
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// ReasoningBlock is a block of a model's extended thinking. OpenAI messages
+// have no place for it, so it is only returned by the Anthropic API, whose
+// clients send it back with the assistant turn. Redacted holds reasoning the
+// provider encrypted, which is returned as is.
+type ReasoningBlock struct {
+	Text      string
+	Signature string
+	Redacted  []byte
+}
+
+// ReasoningDelta is a piece of a streamed ReasoningBlock.
+type ReasoningDelta ReasoningBlock
+
+func toReasoningBlock(block types.ReasoningContentBlock) (ReasoningBlock, bool) {
+	switch reasoning := block.(type) {
+	case *types.ReasoningContentBlockMemberReasoningText:
+		return ReasoningBlock{
+			Text:      aws.ToString(reasoning.Value.Text),
+			Signature: aws.ToString(reasoning.Value.Signature),
+		}, true
+	case *types.ReasoningContentBlockMemberRedactedContent:
+		return ReasoningBlock{Redacted: reasoning.Value}, true
+	default:
+		return ReasoningBlock{}, false
+	}
+}
+
+// makeReasoningBlocks hands a turn's reasoning back to the model, which
+// Bedrock requires before the tool results of a turn that thought.
+func makeReasoningBlocks(reasoning []ReasoningBlock) []types.ContentBlock {
+	blocks := make([]types.ContentBlock, 0, len(reasoning))
+	for _, block := range reasoning {
+		if block.Redacted != nil {
+			blocks = append(blocks, &types.ContentBlockMemberReasoningContent{
+				Value: &types.ReasoningContentBlockMemberRedactedContent{Value: block.Redacted},
+			})
+			continue
+		}
+		text := types.ReasoningTextBlock{Text: aws.String(block.Text)}
+		if block.Signature != "" {
+			text.Signature = aws.String(block.Signature)
+		}
+		blocks = append(blocks, &types.ContentBlockMemberReasoningContent{
+			Value: &types.ReasoningContentBlockMemberReasoningText{Value: text},
+		})
+	}
+	return blocks
+}
+
+// ReasoningDelta returns the reasoning carried by a Bedrock event, which
+// ToOpenAIResponseChunk leaves out.
+func (c *ChunkConverter) ReasoningDelta(bedrockChunk types.ConverseStreamOutput) (ReasoningDelta, bool) {
+	output, ok := bedrockChunk.(*types.ConverseStreamOutputMemberContentBlockDelta)
+	if !ok {
+		return ReasoningDelta{}, false
+	}
+	reasoning, ok := output.Value.Delta.(*types.ContentBlockDeltaMemberReasoningContent)
+	if !ok {
+		return ReasoningDelta{}, false
+	}
+
+	switch delta := reasoning.Value.(type) {
+	case *types.ReasoningContentBlockDeltaMemberText:
+		return ReasoningDelta{Text: delta.Value}, true
+	case *types.ReasoningContentBlockDeltaMemberSignature:
+		return ReasoningDelta{Signature: delta.Value}, true
+	case *types.ReasoningContentBlockDeltaMemberRedactedContent:
+		return ReasoningDelta{Redacted: delta.Value}, true
+	default:
+		return ReasoningDelta{}, false
+	}
+}
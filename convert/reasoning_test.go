@@ -0,0 +1,101 @@
+package convert
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToBedrockRequestReasoning(t *testing.T) {
+	input := OpenAIRequest{
+		Model: "anthropic.claude-3-7-sonnet-20250219-v1:0",
+		Tools: []OpenAITool{{Type: "function", Function: OpenAIFunction{Name: "lookup"}}},
+		Messages: []OpenAIMessage{
+			{Role: "user", Content: "What is this?"},
+			{
+				Role:      "assistant",
+				ToolCalls: []OpenAIToolCall{{ID: "toolu_1", Type: "function", Function: OpenAIFunctionCall{Name: "lookup", Arguments: "{}"}}},
+				Reasoning: []ReasoningBlock{{Text: "Hmm", Signature: "sig"}, {Redacted: []byte{1, 2, 3}}},
+			},
+			{Role: "tool", ToolCallID: "toolu_1", Content: "a cat"},
+		},
+	}
+
+	result, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+	require.NoError(t, err)
+
+	require.Len(t, result.Messages, 3)
+	assistant := result.Messages[1].Content
+	require.Len(t, assistant, 3)
+	assert.Equal(t, &types.ContentBlockMemberReasoningContent{
+		Value: &types.ReasoningContentBlockMemberReasoningText{
+			Value: types.ReasoningTextBlock{Text: aws.String("Hmm"), Signature: aws.String("sig")},
+		},
+	}, assistant[0])
+	assert.Equal(t, &types.ContentBlockMemberReasoningContent{
+		Value: &types.ReasoningContentBlockMemberRedactedContent{Value: []byte{1, 2, 3}},
+	}, assistant[1])
+	assert.IsType(t, &types.ContentBlockMemberToolUse{}, assistant[2])
+}
+
+func TestToOpenAIResponseReasoning(t *testing.T) {
+	result := ToOpenAIResponse(&bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{
+				Role: types.ConversationRoleAssistant,
+				Content: []types.ContentBlock{
+					&types.ContentBlockMemberReasoningContent{
+						Value: &types.ReasoningContentBlockMemberReasoningText{
+							Value: types.ReasoningTextBlock{Text: aws.String("Hmm"), Signature: aws.String("sig")},
+						},
+					},
+					&types.ContentBlockMemberText{Value: "A cat."},
+				},
+			},
+		},
+		StopReason: types.StopReasonEndTurn,
+	}, "claude")
+
+	require.Len(t, result.Choices, 1)
+	assert.Equal(t, "A cat.", result.Choices[0].Message.Content)
+	assert.Equal(t, []ReasoningBlock{{Text: "Hmm", Signature: "sig"}}, result.Choices[0].Message.Reasoning)
+}
+
+func TestChunkConverterReasoningDelta(t *testing.T) {
+	converter := NewChunkConverter("claude")
+	delta := func(reasoning types.ReasoningContentBlockDelta) types.ConverseStreamOutput {
+		return &types.ConverseStreamOutputMemberContentBlockDelta{
+			Value: types.ContentBlockDeltaEvent{
+				ContentBlockIndex: aws.Int32(0),
+				Delta:             &types.ContentBlockDeltaMemberReasoningContent{Value: reasoning},
+			},
+		}
+	}
+
+	tests := []struct {
+		event    types.ConverseStreamOutput
+		expected ReasoningDelta
+	}{
+		{delta(&types.ReasoningContentBlockDeltaMemberText{Value: "Hmm"}), ReasoningDelta{Text: "Hmm"}},
+		{delta(&types.ReasoningContentBlockDeltaMemberSignature{Value: "sig"}), ReasoningDelta{Signature: "sig"}},
+		{delta(&types.ReasoningContentBlockDeltaMemberRedactedContent{Value: []byte{1}}), ReasoningDelta{Redacted: []byte{1}}},
+	}
+	for _, tt := range tests {
+		_, ok := converter.ToOpenAIResponseChunk(tt.event)
+		assert.False(t, ok)
+		reasoning, ok := converter.ReasoningDelta(tt.event)
+		require.True(t, ok)
+		assert.Equal(t, tt.expected, reasoning)
+	}
+
+	_, ok := converter.ReasoningDelta(&types.ConverseStreamOutputMemberContentBlockDelta{
+		Value: types.ContentBlockDeltaEvent{Delta: &types.ContentBlockDeltaMemberText{Value: "Hi"}},
+	})
+	assert.False(t, ok)
+}
@@ -1,6 +1,10 @@
 package convert
 
 import (
+	"context"
+	"log/slog"
+	"strings"
+
 	"github.com/DefangLabs/bedrock-sidecar/bedrock"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
@@ -11,65 +15,173 @@ type OpenAIRequest struct {
 	Model          string                 `json:"model"`
 	N              int                    `json:"n"`
 	MaxTokens      int                    `json:"max_tokens,omitempty"`
-	ResponseFormat string                 `json:"response_format,omitempty"`
+	ResponseFormat *OpenAIResponseFormat  `json:"response_format,omitempty"`
 	Messages       []OpenAIMessage        `json:"messages"`
 	Seed           int                    `json:"seed,omitempty"`
 	Stop           []string               `json:"stop,omitempty"`
 	Stream         bool                   `json:"stream,omitempty"`
+	StreamOptions  *StreamOptions         `json:"stream_options,omitempty"`
 	Temperature    *float64               `json:"temperature,omitempty"`
 	TopP           *float64               `json:"top_p,omitempty"`
+	Tools          []OpenAITool           `json:"tools,omitempty"`
+	ToolChoice     *OpenAIToolChoice      `json:"tool_choice,omitempty"`
 	Extra          map[string]interface{} `json:"-"`
+	// ModelFields are native parameters of the model, such as Anthropic's
+	// thinking and top_k, which are always forwarded to it.
+	ModelFields map[string]interface{} `json:"-"`
 }
 
 type OpenAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role         string              `json:"role"`
+	Content      string              `json:"content"`
+	ContentParts []OpenAIContentPart `json:"-"`
+	ToolCalls    []OpenAIToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID   string              `json:"tool_call_id,omitempty"`
+	// Reasoning is the extended thinking of an assistant turn.
+	Reasoning []ReasoningBlock `json:"-"`
 }
 
-func ToBedrockRequest(modelMap bedrock.ModelMap, openAIReq OpenAIRequest) bedrockruntime.ConverseInput {
-	systemMessages, messages := partitionSystemMessages(openAIReq.Messages)
+func ToBedrockRequest(ctx context.Context, modelMap bedrock.ModelMap, openAIReq OpenAIRequest) (bedrockruntime.ConverseInput, error) {
+	systemMessages, messages, err := partitionSystemMessages(ctx, openAIReq.conversation())
+	if err != nil {
+		return bedrockruntime.ConverseInput{}, err
+	}
 
-	return bedrockruntime.ConverseInput{
-		InferenceConfig: makeInferenceConfig(openAIReq),
-		Messages:        messages,
-		ModelId:         aws.String(modelMap.BedrockModelID(openAIReq.Model)),
-		System:          makeSystem(systemMessages),
+	toolConfig, err := makeToolConfig(openAIReq)
+	if err != nil {
+		return bedrockruntime.ConverseInput{}, err
+	}
+
+	modelConfig, err := modelMap.Resolve(openAIReq.Model)
+	if err != nil {
+		return bedrockruntime.ConverseInput{}, err
 	}
+
+	return bedrockruntime.ConverseInput{
+		AdditionalModelRequestFields: makeAdditionalModelRequestFields(modelConfig, openAIReq),
+		GuardrailConfig:              makeGuardrailConfig(modelConfig),
+		InferenceConfig:              makeInferenceConfig(modelConfig, openAIReq),
+		Messages:                     messages,
+		ModelId:                      aws.String(modelConfig.ModelID),
+		System:                       makeSystem(systemMessages),
+		ToolConfig:                   toolConfig,
+	}, nil
 }
 
-func ToBedrockStreamRequest(modelMap bedrock.ModelMap, openAIReq OpenAIRequest) bedrockruntime.ConverseStreamInput {
-	systemMessages, messages := partitionSystemMessages(openAIReq.Messages)
+func ToBedrockStreamRequest(ctx context.Context, modelMap bedrock.ModelMap, openAIReq OpenAIRequest) (bedrockruntime.ConverseStreamInput, error) {
+	systemMessages, messages, err := partitionSystemMessages(ctx, openAIReq.conversation())
+	if err != nil {
+		return bedrockruntime.ConverseStreamInput{}, err
+	}
+
+	toolConfig, err := makeToolConfig(openAIReq)
+	if err != nil {
+		return bedrockruntime.ConverseStreamInput{}, err
+	}
+
+	modelConfig, err := modelMap.Resolve(openAIReq.Model)
+	if err != nil {
+		return bedrockruntime.ConverseStreamInput{}, err
+	}
 
 	return bedrockruntime.ConverseStreamInput{
-		InferenceConfig: makeInferenceConfig(openAIReq),
-		Messages:        messages,
-		ModelId:         aws.String(modelMap.BedrockModelID(openAIReq.Model)),
-		System:          makeSystem(systemMessages),
+		AdditionalModelRequestFields: makeAdditionalModelRequestFields(modelConfig, openAIReq),
+		GuardrailConfig:              makeGuardrailStreamConfig(modelConfig),
+		InferenceConfig:              makeInferenceConfig(modelConfig, openAIReq),
+		Messages:                     messages,
+		ModelId:                      aws.String(modelConfig.ModelID),
+		System:                       makeSystem(systemMessages),
+		ToolConfig:                   toolConfig,
+	}, nil
+}
+
+// makeGuardrailConfig applies the model's guardrail, with tracing enabled
+// so that its assessments can be reported back.
+func makeGuardrailConfig(config bedrock.ModelConfig) *types.GuardrailConfiguration {
+	if config.Guardrail == nil {
+		return nil
+	}
+	return &types.GuardrailConfiguration{
+		GuardrailIdentifier: aws.String(config.Guardrail.ID),
+		GuardrailVersion:    aws.String(config.Guardrail.Version),
+		Trace:               types.GuardrailTraceEnabled,
 	}
 }
 
-func partitionSystemMessages(openAIMessages []OpenAIMessage) ([]types.Message, []types.Message) {
+func makeGuardrailStreamConfig(config bedrock.ModelConfig) *types.GuardrailStreamConfiguration {
+	if config.Guardrail == nil {
+		return nil
+	}
+	return &types.GuardrailStreamConfiguration{
+		GuardrailIdentifier: aws.String(config.Guardrail.ID),
+		GuardrailVersion:    aws.String(config.Guardrail.Version),
+		Trace:               types.GuardrailTraceEnabled,
+	}
+}
+
+func partitionSystemMessages(ctx context.Context, openAIMessages []OpenAIMessage) ([]types.Message, []types.Message, error) {
 	systemMessages := make([]types.Message, 0, 1)
 	messages := make([]types.Message, 0, len(openAIMessages))
 
 	for _, msg := range openAIMessages {
-		bedrockMessage := types.Message{
-			Role: types.ConversationRole(msg.Role),
-			Content: []types.ContentBlock{
-				&types.ContentBlockMemberText{
-					Value: msg.Content,
+		if msg.Role == "tool" {
+			toolResult, err := makeToolResultBlock(msg)
+			if err != nil {
+				return nil, nil, err
+			}
+			messages = append(messages, types.Message{
+				Role:    types.ConversationRoleUser,
+				Content: []types.ContentBlock{toolResult},
+			})
+			continue
+		}
+
+		if msg.Role == "system" || msg.Role == "developer" {
+			if msg.Role == "developer" {
+				slog.Debug("Normalized conversation: mapped developer message to system")
+			}
+			text := msg.contentText()
+			if strings.TrimSpace(text) == "" {
+				slog.Debug("Normalized conversation: dropped empty system message")
+				continue
+			}
+			systemMessages = append(systemMessages, types.Message{
+				Role: "system",
+				Content: []types.ContentBlock{
+					&types.ContentBlockMemberText{
+						Value: text,
+					},
 				},
-			},
+			})
+			continue
 		}
 
-		if msg.Role == "system" {
-			systemMessages = append(systemMessages, bedrockMessage)
-		} else {
-			messages = append(messages, bedrockMessage)
+		content, err := makeContentBlocks(ctx, msg)
+		if err != nil {
+			return nil, nil, err
 		}
+		content = append(makeReasoningBlocks(msg.Reasoning), content...)
+		for _, toolCall := range msg.ToolCalls {
+			toolUse, err := makeToolUseBlock(toolCall)
+			if err != nil {
+				return nil, nil, err
+			}
+			content = append(content, toolUse)
+		}
+
+		messages = append(messages, types.Message{
+			Role:    types.ConversationRole(msg.Role),
+			Content: content,
+		})
+	}
+
+	messages = normalizeMessages(messages)
+
+	if err := checkDocuments(messages); err != nil {
+		return nil, nil, err
 	}
 
-	return systemMessages, messages
+	return systemMessages, messages, nil
 }
 
 func makeSystem(systemMessages []types.Message) []types.SystemContentBlock {
@@ -84,25 +196,42 @@ func makeSystem(systemMessages []types.Message) []types.SystemContentBlock {
 	return system
 }
 
-func makeInferenceConfig(openAIReq OpenAIRequest) *types.InferenceConfiguration {
+// makeInferenceConfig applies the model's inference parameters to those of
+// the request.
+func makeInferenceConfig(config bedrock.ModelConfig, openAIReq OpenAIRequest) *types.InferenceConfiguration {
+	params := bedrock.InferenceParams{}
+	if config.Inference != nil {
+		params = *config.Inference
+	}
+
+	var requestedMaxTokens *int
+	if openAIReq.MaxTokens != 0 {
+		requestedMaxTokens = &openAIReq.MaxTokens
+	}
+
 	var temperature *float32
 	var maxTokens *int32
 	var topP *float32
-	if openAIReq.Temperature != nil {
-		temperature = aws.Float32(float32(*openAIReq.Temperature))
+	if value := params.Temperature.Apply(openAIReq.Temperature); value != nil && !params.Strips("temperature") {
+		temperature = aws.Float32(float32(*value))
 	}
 
-	if openAIReq.MaxTokens != 0 {
-		maxTokens = aws.Int32(int32(openAIReq.MaxTokens)) //nolint:gosec
+	if value := params.MaxTokens.Apply(requestedMaxTokens); value != nil && !params.Strips("max_tokens") {
+		maxTokens = aws.Int32(int32(*value)) //nolint:gosec
+	}
+
+	if value := params.TopP.Apply(openAIReq.TopP); value != nil && !params.Strips("top_p") {
+		topP = aws.Float32(float32(*value))
 	}
 
-	if openAIReq.TopP != nil {
-		topP = aws.Float32(float32(*openAIReq.TopP))
+	var stopSequences []string
+	if !params.Strips("stop") {
+		stopSequences = params.Stop.Apply(openAIReq.Stop)
 	}
 
 	return &types.InferenceConfiguration{
 		MaxTokens:     maxTokens,
-		StopSequences: openAIReq.Stop,
+		StopSequences: stopSequences,
 		Temperature:   temperature,
 		TopP:          topP,
 	}
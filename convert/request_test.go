@@ -1,24 +1,28 @@
 package convert
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
 
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestToBedrockRequest(t *testing.T) {
 	tests := []struct {
 		name     string
-		modelMap map[string]string
+		modelMap bedrock.ModelMap
 		input    OpenAIRequest
 		expected bedrockruntime.ConverseInput
 	}{
 		{
 			name:     "basic conversion",
-			modelMap: map[string]string{},
+			modelMap: bedrock.ModelMap{},
 			input: OpenAIRequest{
 				Model:     "anthropic.claude-v2",
 				MaxTokens: 1000,
@@ -57,7 +61,7 @@ func TestToBedrockRequest(t *testing.T) {
 		},
 		{
 			name:     "empty optional fields",
-			modelMap: map[string]string{},
+			modelMap: bedrock.ModelMap{},
 			input: OpenAIRequest{
 				Model: "anthropic.claude-v2",
 				Messages: []OpenAIMessage{
@@ -85,7 +89,8 @@ func TestToBedrockRequest(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := ToBedrockRequest(tt.modelMap, tt.input)
+			result, err := ToBedrockRequest(context.Background(), tt.modelMap, tt.input)
+			require.NoError(t, err)
 
 			assert.Equal(t, tt.expected.ModelId, result.ModelId)
 
@@ -107,13 +112,13 @@ func TestToBedrockRequest(t *testing.T) {
 func TestToBedrockStreamRequest(t *testing.T) {
 	tests := []struct {
 		name     string
-		modelMap map[string]string
+		modelMap bedrock.ModelMap
 		input    OpenAIRequest
 		expected bedrockruntime.ConverseStreamInput
 	}{
 		{
 			name:     "basic conversion",
-			modelMap: map[string]string{},
+			modelMap: bedrock.ModelMap{},
 			input: OpenAIRequest{
 				Model:     "anthropic.claude-v2",
 				MaxTokens: 1000,
@@ -152,7 +157,7 @@ func TestToBedrockStreamRequest(t *testing.T) {
 		},
 		{
 			name:     "empty optional fields",
-			modelMap: map[string]string{},
+			modelMap: bedrock.ModelMap{},
 			input: OpenAIRequest{
 				Model: "anthropic.claude-v2",
 				Messages: []OpenAIMessage{
@@ -180,7 +185,8 @@ func TestToBedrockStreamRequest(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := ToBedrockStreamRequest(tt.modelMap, tt.input)
+			result, err := ToBedrockStreamRequest(context.Background(), tt.modelMap, tt.input)
+			require.NoError(t, err)
 
 			assert.Equal(t, tt.expected.ModelId, result.ModelId)
 
@@ -198,3 +204,82 @@ func TestToBedrockStreamRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestToBedrockRequestInferenceParams(t *testing.T) {
+	var modelMap bedrock.ModelMap
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"claude": {"model": "anthropic.claude-v2", "passthrough": ["*"], "inference": {
+			"max_tokens": {"default": 4096, "max": 8192},
+			"temperature": {"min": 0.2, "max": 1},
+			"stop": {"default": ["\n\nHuman:"], "max": 2},
+			"strip": ["top_p", "top_k"]
+		}},
+		"nova": {"model": "amazon.nova-lite-v1:0", "inference": {
+			"temperature": {"force": 0},
+			"stop": {"force": []}
+		}}
+	}`), &modelMap.Aliases))
+	messages := []OpenAIMessage{{Role: "user", Content: "Hello"}}
+
+	tests := []struct {
+		name     string
+		input    OpenAIRequest
+		expected types.InferenceConfiguration
+	}{
+		{
+			name:  "defaults",
+			input: OpenAIRequest{Model: "claude", Messages: messages},
+			expected: types.InferenceConfiguration{
+				MaxTokens:     aws.Int32(4096),
+				StopSequences: []string{"\n\nHuman:"},
+			},
+		},
+		{
+			name: "clamped and stripped",
+			input: OpenAIRequest{
+				Model:       "claude",
+				MaxTokens:   100000,
+				Temperature: aws.Float64(0),
+				TopP:        aws.Float64(0.9),
+				Stop:        []string{"a", "b", "c"},
+				Messages:    messages,
+			},
+			expected: types.InferenceConfiguration{
+				MaxTokens:     aws.Int32(8192),
+				Temperature:   aws.Float32(0.2),
+				StopSequences: []string{"a", "b"},
+			},
+		},
+		{
+			name:  "forced",
+			input: OpenAIRequest{Model: "nova", Temperature: aws.Float64(0.7), Stop: []string{"a"}, Messages: messages},
+			expected: types.InferenceConfiguration{
+				Temperature:   aws.Float32(0),
+				StopSequences: []string{},
+			},
+		},
+		{
+			name:     "unmapped",
+			input:    OpenAIRequest{Model: "other", MaxTokens: 10, Messages: messages},
+			expected: types.InferenceConfiguration{MaxTokens: aws.Int32(10)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ToBedrockRequest(context.Background(), modelMap, tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, &tt.expected, result.InferenceConfig)
+		})
+	}
+
+	t.Run("stripped extra fields", func(t *testing.T) {
+		input := OpenAIRequest{Model: "claude", Extra: map[string]interface{}{"top_k": 50, "safe_prompt": true}, Messages: messages}
+		result, err := ToBedrockStreamRequest(context.Background(), modelMap, input)
+		require.NoError(t, err)
+
+		data, err := result.AdditionalModelRequestFields.MarshalSmithyDocument()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"safe_prompt":true}`, string(data))
+	})
+}
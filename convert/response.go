@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 	"github.com/openai/openai-go"
@@ -24,12 +25,8 @@ type Choice struct {
 	Index        int           `json:"index"`
 	Message      OpenAIMessage `json:"message"`
 	FinishReason string        `json:"finish_reason"`
-}
-
-type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	// Guardrail is the assessment of the model's guardrail, if it has one.
+	Guardrail *types.GuardrailTraceAssessment `json:"-"`
 }
 
 type TimeProvider func() time.Time
@@ -68,13 +65,31 @@ func ToOpenAIResponse(bedrockOutput *bedrockruntime.ConverseOutput, model string
 		}
 	}
 	var content []string
+	var toolCalls []OpenAIToolCall
+	var reasoning []ReasoningBlock
 	for _, cntnt := range message.Value.Content {
-		if textContent, ok := cntnt.(*types.ContentBlockMemberText); ok {
-			content = append(content, textContent.Value)
-			break
+		switch block := cntnt.(type) {
+		case *types.ContentBlockMemberText:
+			content = append(content, block.Value)
+		case *types.ContentBlockMemberReasoningContent:
+			if reasoningBlock, ok := toReasoningBlock(block.Value); ok {
+				reasoning = append(reasoning, reasoningBlock)
+			}
+		case *types.ContentBlockMemberToolUse:
+			toolCall := toOpenAIToolCall(block.Value)
+			if toolCall.Function.Name == structuredOutputToolName {
+				content = append(content, toolCall.Function.Arguments)
+				continue
+			}
+			toolCalls = append(toolCalls, toolCall)
 		}
 	}
 
+	finishReason := mapStopReasonToFinishReason(bedrockOutput.StopReason)
+	if finishReason == "tool_calls" && len(toolCalls) == 0 {
+		finishReason = "stop"
+	}
+
 	return OpenAIResponse{
 		ID:      generateID(),
 		Object:  "chat.completion",
@@ -84,59 +99,146 @@ func ToOpenAIResponse(bedrockOutput *bedrockruntime.ConverseOutput, model string
 			{
 				Index: 0,
 				Message: OpenAIMessage{
-					Role:    "assistant",
-					Content: strings.Join(content, "\n\n"),
+					Role:      "assistant",
+					Content:   strings.Join(content, "\n\n"),
+					ToolCalls: toolCalls,
+					Reasoning: reasoning,
 				},
-				FinishReason: string(bedrockOutput.StopReason),
+				FinishReason: string(finishReason),
+				Guardrail:    guardrailAssessment(bedrockOutput.Trace),
 			},
 		},
-		Usage: Usage{
-			PromptTokens:     0, // TODO: Implement token counting
-			CompletionTokens: 0,
-			TotalTokens:      0,
-		},
+		Usage: makeUsage(bedrockOutput.Usage),
+	}
+}
+
+// MergeOpenAIResponses combines single-choice responses into one response
+// with a choice per input, as returned for requests with n > 1.
+func MergeOpenAIResponses(responses []OpenAIResponse) OpenAIResponse {
+	merged := responses[0]
+	merged.Choices = make([]Choice, 0, len(responses))
+	merged.Usage = Usage{}
+
+	for i, resp := range responses {
+		for _, choice := range resp.Choices {
+			choice.Index = i
+			merged.Choices = append(merged.Choices, choice)
+		}
+		merged.Usage = merged.Usage.Add(resp.Usage)
+	}
+
+	return merged
+}
+
+// ChunkConverter converts the events of a single Bedrock stream. It keeps
+// track of tool-use content blocks so that each one maps onto a stable
+// tool_calls index, even when text and tool blocks are interleaved.
+type ChunkConverter struct {
+	identity         StreamIdentity
+	model            string
+	toolCallIndexes  map[int32]int64
+	structuredBlocks map[int32]bool
+	usage            Usage
+	guardrail        *types.GuardrailTraceAssessment
+}
+
+// StreamIdentity is the id and creation time shared by every chunk of one
+// streamed completion, across all of its choices.
+type StreamIdentity struct {
+	ID      string
+	Created int64
+}
+
+func NewStreamIdentity() StreamIdentity {
+	return StreamIdentity{
+		ID:      generateID(),
+		Created: timeProvider().Unix(),
+	}
+}
+
+func NewChunkConverter(model string) *ChunkConverter {
+	return NewChunkConverterWithIdentity(NewStreamIdentity(), model)
+}
+
+func NewChunkConverterWithIdentity(identity StreamIdentity, model string) *ChunkConverter {
+	return &ChunkConverter{
+		identity:         identity,
+		model:            model,
+		toolCallIndexes:  map[int32]int64{},
+		structuredBlocks: map[int32]bool{},
 	}
 }
 
 func ToOpenAIResponseChunk(bedrockChunk types.ConverseStreamOutput, model string) openai.ChatCompletionChunk {
-	now := timeProvider()
+	chunk, _ := NewChunkConverter(model).ToOpenAIResponseChunk(bedrockChunk)
+	return chunk
+}
 
-	choice := makeOpenAIChatCompletionChunkChoice(bedrockChunk)
+// ToOpenAIResponseChunk converts one Bedrock event. It reports false for
+// events that have no OpenAI counterpart, which should not be sent.
+func (c *ChunkConverter) ToOpenAIResponseChunk(bedrockChunk types.ConverseStreamOutput) (openai.ChatCompletionChunk, bool) {
+	choice, ok := c.makeOpenAIChatCompletionChunkChoice(bedrockChunk)
 
 	return openai.ChatCompletionChunk{
-		ID:      generateID(),
+		ID:      c.identity.ID,
 		Object:  "chat.completion.chunk",
-		Created: now.Unix(),
-		Model:   model,
+		Created: c.identity.Created,
+		Model:   c.model,
 		Choices: []openai.ChatCompletionChunkChoice{
 			choice,
 		},
+	}, ok
+}
+
+// Usage returns the token usage reported by the stream's metadata event.
+func (c *ChunkConverter) Usage() Usage {
+	return c.usage
+}
+
+// Guardrail returns the guardrail assessment reported by the stream's
+// metadata event, if any.
+func (c *ChunkConverter) Guardrail() *types.GuardrailTraceAssessment {
+	return c.guardrail
+}
+
+func guardrailAssessment(trace *types.ConverseTrace) *types.GuardrailTraceAssessment {
+	if trace == nil {
+		return nil
 	}
+	return trace.Guardrail
 }
 
-func makeOpenAIChatCompletionChunkChoice(bedrockChunk types.ConverseStreamOutput) openai.ChatCompletionChunkChoice {
+func (c *ChunkConverter) makeOpenAIChatCompletionChunkChoice(bedrockChunk types.ConverseStreamOutput) (openai.ChatCompletionChunkChoice, bool) {
 	choice := openai.ChatCompletionChunkChoice{}
 
 	switch output := bedrockChunk.(type) {
 	case *types.ConverseStreamOutputMemberContentBlockStart:
-		slog.Warn("handling of ConverseStreamOutputMemberContentBlockStart in unimplemented")
+		return c.handleContentBlockStart(output)
 	case *types.ConverseStreamOutputMemberContentBlockStop:
-		slog.Warn("handling of ConverseStreamOutputMemberContentBlockStop in unimplemented")
+		return choice, false
 	case *types.ConverseStreamOutputMemberMetadata:
-		slog.Warn("handling of ConverseStreamOutputMemberMetadata in unimplemented")
+		c.usage = makeUsage(output.Value.Usage)
+		if output.Value.Trace != nil {
+			c.guardrail = output.Value.Trace.Guardrail
+		}
+		return choice, false
 	case *types.ConverseStreamOutputMemberMessageStart:
 		choice.Delta = openai.ChatCompletionChunkChoicesDelta{
 			Role: openai.ChatCompletionChunkChoicesDeltaRole(output.Value.Role),
 		}
 	case *types.ConverseStreamOutputMemberMessageStop:
 		choice.FinishReason = mapStopReasonToFinishReason(output.Value.StopReason)
+		if choice.FinishReason == "tool_calls" && len(c.toolCallIndexes) == 0 {
+			choice.FinishReason = "stop"
+		}
 	case *types.ConverseStreamOutputMemberContentBlockDelta:
-		choice = handleContentBlockDelta(output)
+		return c.handleContentBlockDelta(output)
 	default:
 		slog.Warn("union is nil or unknown type")
+		return choice, false
 	}
 
-	return choice
+	return choice, true
 }
 
 func mapStopReasonToFinishReason(stopReason types.StopReason) openai.ChatCompletionChunkChoicesFinishReason {
@@ -154,9 +256,41 @@ func mapStopReasonToFinishReason(stopReason types.StopReason) openai.ChatComplet
 	}
 }
 
-func handleContentBlockDelta(
+func (c *ChunkConverter) handleContentBlockStart(
+	output *types.ConverseStreamOutputMemberContentBlockStart,
+) (openai.ChatCompletionChunkChoice, bool) {
+	choice := openai.ChatCompletionChunkChoice{}
+	toolUse, ok := output.Value.Start.(*types.ContentBlockStartMemberToolUse)
+	if !ok {
+		slog.Warn("unknown ContentBlockStart type")
+		return choice, false
+	}
+
+	if aws.ToString(toolUse.Value.Name) == structuredOutputToolName {
+		c.structuredBlocks[aws.ToInt32(output.Value.ContentBlockIndex)] = true
+		return choice, false
+	}
+
+	index := int64(len(c.toolCallIndexes))
+	c.toolCallIndexes[aws.ToInt32(output.Value.ContentBlockIndex)] = index
+	choice.Delta = openai.ChatCompletionChunkChoicesDelta{
+		ToolCalls: []openai.ChatCompletionChunkChoicesDeltaToolCall{
+			{
+				Index: index,
+				ID:    aws.ToString(toolUse.Value.ToolUseId),
+				Type:  openai.ChatCompletionChunkChoicesDeltaToolCallsTypeFunction,
+				Function: openai.ChatCompletionChunkChoicesDeltaToolCallsFunction{
+					Name: aws.ToString(toolUse.Value.Name),
+				},
+			},
+		},
+	}
+	return choice, true
+}
+
+func (c *ChunkConverter) handleContentBlockDelta(
 	output *types.ConverseStreamOutputMemberContentBlockDelta,
-) openai.ChatCompletionChunkChoice {
+) (openai.ChatCompletionChunkChoice, bool) {
 	choice := openai.ChatCompletionChunkChoice{}
 	switch delta := output.Value.Delta.(type) {
 	case *types.ContentBlockDeltaMemberText:
@@ -164,11 +298,34 @@ func handleContentBlockDelta(
 			Content: delta.Value,
 		}
 	case *types.ContentBlockDeltaMemberReasoningContent:
-		slog.Warn("handling of ContentBlockDeltaMemberReasoningContent in unimplemented")
+		// OpenAI chunks have no reasoning, see ReasoningDelta.
+		return choice, false
 	case *types.ContentBlockDeltaMemberToolUse:
-		slog.Warn("handling of ContentBlockDeltaMemberReasoningContent in unimplemented")
+		if c.structuredBlocks[aws.ToInt32(output.Value.ContentBlockIndex)] {
+			choice.Delta = openai.ChatCompletionChunkChoicesDelta{
+				Content: aws.ToString(delta.Value.Input),
+			}
+			return choice, true
+		}
+		index, ok := c.toolCallIndexes[aws.ToInt32(output.Value.ContentBlockIndex)]
+		if !ok {
+			slog.Warn("received tool use delta for unknown content block", "index", aws.ToInt32(output.Value.ContentBlockIndex))
+			return choice, false
+		}
+		choice.Delta = openai.ChatCompletionChunkChoicesDelta{
+			ToolCalls: []openai.ChatCompletionChunkChoicesDeltaToolCall{
+				{
+					Index: index,
+					Function: openai.ChatCompletionChunkChoicesDeltaToolCallsFunction{
+						Arguments: aws.ToString(delta.Value.Input),
+					},
+				},
+			},
+		}
+	default:
+		return choice, false
 	}
-	return choice
+	return choice, true
 }
 
 func generateID() string {
@@ -0,0 +1,97 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// structuredOutputToolName is the synthetic tool used to implement
+// response_format. Its input is returned to the client as message content
+// instead of a tool call, so clients may not declare a tool with this name.
+const structuredOutputToolName = "json_response_format"
+
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *OpenAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type OpenAIJSONSchema struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema,omitempty"`
+	Strict      *bool                  `json:"strict,omitempty"`
+}
+
+// UnmarshalJSON also accepts the bare string form, e.g. "json_object".
+func (f *OpenAIResponseFormat) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &f.Type)
+	}
+
+	type alias OpenAIResponseFormat
+	return json.Unmarshal(data, (*alias)(f))
+}
+
+func (f *OpenAIResponseFormat) structured() bool {
+	return f != nil && (f.Type == "json_object" || f.Type == "json_schema")
+}
+
+// check rejects formats other than text and the structured ones, which
+// would otherwise be ignored.
+func (f *OpenAIResponseFormat) check() error {
+	if f == nil || f.Type == "text" || f.structured() {
+		return nil
+	}
+	return fmt.Errorf("%w: unsupported response_format %q", ErrInvalidToolCall, f.Type)
+}
+
+func makeStructuredOutputTool(format *OpenAIResponseFormat) (types.Tool, error) {
+	description := "Respond to the user by calling this tool with the complete answer as its input."
+	schema := map[string]interface{}{"type": "object"}
+
+	switch format.Type {
+	case "json_object":
+	case "json_schema":
+		if format.JSONSchema == nil || format.JSONSchema.Schema == nil {
+			return nil, fmt.Errorf("%w: response_format json_schema requires a schema", ErrInvalidToolCall)
+		}
+		if schemaType, ok := format.JSONSchema.Schema["type"]; ok && schemaType != "object" {
+			return nil, fmt.Errorf("%w: response_format json_schema must describe an object", ErrInvalidToolCall)
+		}
+		schema = format.JSONSchema.Schema
+		if format.JSONSchema.Description != "" {
+			description += " " + format.JSONSchema.Description
+		}
+	}
+
+	return &types.ToolMemberToolSpec{
+		Value: types.ToolSpecification{
+			Name:        aws.String(structuredOutputToolName),
+			Description: aws.String(description),
+			InputSchema: &types.ToolInputSchemaMemberJson{
+				Value: document.NewLazyDocument(schema),
+			},
+		},
+	}, nil
+}
+
+// structuredOutputToolChoice forces the model to call a tool. When the
+// client offered its own tools the model may call one of those instead,
+// otherwise it has to answer through the structured output tool.
+func structuredOutputToolChoice(openAIReq OpenAIRequest, toolChoice types.ToolChoice) types.ToolChoice {
+	if len(openAIReq.Tools) == 0 || (openAIReq.ToolChoice != nil && openAIReq.ToolChoice.Type == "none") {
+		return &types.ToolChoiceMemberTool{
+			Value: types.SpecificToolChoice{Name: aws.String(structuredOutputToolName)},
+		}
+	}
+	if _, ok := toolChoice.(*types.ToolChoiceMemberTool); ok {
+		return toolChoice
+	}
+	return &types.ToolChoiceMemberAny{}
+}
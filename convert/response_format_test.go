@@ -0,0 +1,174 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIResponseFormatUnmarshalJSON(t *testing.T) {
+	var req OpenAIRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "gpt-4o",
+		"messages": [],
+		"response_format": {
+			"type": "json_schema",
+			"json_schema": {
+				"name": "weather",
+				"strict": true,
+				"schema": {"type": "object", "properties": {"temp": {"type": "number"}}}
+			}
+		}
+	}`), &req))
+
+	require.NotNil(t, req.ResponseFormat)
+	assert.Equal(t, "json_schema", req.ResponseFormat.Type)
+	assert.Equal(t, "weather", req.ResponseFormat.JSONSchema.Name)
+	assert.Equal(t, "object", req.ResponseFormat.JSONSchema.Schema["type"])
+
+	var format OpenAIResponseFormat
+	require.NoError(t, json.Unmarshal([]byte(`"json_object"`), &format))
+	assert.Equal(t, "json_object", format.Type)
+}
+
+func TestToBedrockRequestResponseFormat(t *testing.T) {
+	t.Run("json_object forces the structured output tool", func(t *testing.T) {
+		input := OpenAIRequest{
+			ResponseFormat: &OpenAIResponseFormat{Type: "json_object"},
+			Messages:       []OpenAIMessage{{Role: "user", Content: "Hello"}},
+		}
+
+		result, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+		require.NoError(t, err)
+
+		require.NotNil(t, result.ToolConfig)
+		require.Len(t, result.ToolConfig.Tools, 1)
+		spec := result.ToolConfig.Tools[0].(*types.ToolMemberToolSpec).Value
+		assert.Equal(t, structuredOutputToolName, aws.ToString(spec.Name))
+		assert.Equal(t, &types.ToolChoiceMemberTool{
+			Value: types.SpecificToolChoice{Name: aws.String(structuredOutputToolName)},
+		}, result.ToolConfig.ToolChoice)
+	})
+
+	t.Run("json_schema with client tools requires any tool", func(t *testing.T) {
+		input := OpenAIRequest{
+			Tools: []OpenAITool{{Type: "function", Function: OpenAIFunction{Name: "get_weather"}}},
+			ResponseFormat: &OpenAIResponseFormat{
+				Type: "json_schema",
+				JSONSchema: &OpenAIJSONSchema{
+					Name:   "weather",
+					Schema: map[string]interface{}{"type": "object"},
+				},
+			},
+			Messages: []OpenAIMessage{{Role: "user", Content: "Hello"}},
+		}
+
+		result, err := ToBedrockStreamRequest(context.Background(), bedrock.ModelMap{}, input)
+		require.NoError(t, err)
+
+		require.Len(t, result.ToolConfig.Tools, 2)
+		assert.Equal(t, &types.ToolChoiceMemberAny{}, result.ToolConfig.ToolChoice)
+	})
+
+	t.Run("text is ignored", func(t *testing.T) {
+		input := OpenAIRequest{
+			ResponseFormat: &OpenAIResponseFormat{Type: "text"},
+			Messages:       []OpenAIMessage{{Role: "user", Content: "Hello"}},
+		}
+
+		result, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+		require.NoError(t, err)
+		assert.Nil(t, result.ToolConfig)
+	})
+
+	t.Run("invalid formats", func(t *testing.T) {
+		for _, format := range []*OpenAIResponseFormat{
+			{Type: "json_schema"},
+			{Type: "json_schema", JSONSchema: &OpenAIJSONSchema{Schema: map[string]interface{}{"type": "array"}}},
+			{Type: "xml"},
+			{},
+		} {
+			_, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, OpenAIRequest{ResponseFormat: format})
+			assert.ErrorIs(t, err, ErrInvalidToolCall)
+		}
+	})
+
+	t.Run("reserved tool name", func(t *testing.T) {
+		input := OpenAIRequest{
+			Tools: []OpenAITool{{Type: "function", Function: OpenAIFunction{Name: structuredOutputToolName}}},
+		}
+		_, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+		assert.ErrorIs(t, err, ErrInvalidToolCall)
+	})
+}
+
+func TestToOpenAIResponseStructuredOutput(t *testing.T) {
+	bedrockOutput := &bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{
+				Content: []types.ContentBlock{
+					&types.ContentBlockMemberToolUse{
+						Value: types.ToolUseBlock{
+							ToolUseId: aws.String("tooluse_1"),
+							Name:      aws.String(structuredOutputToolName),
+							Input:     document.NewLazyDocument(map[string]interface{}{"temp": 21}),
+						},
+					},
+				},
+			},
+		},
+		StopReason: types.StopReasonToolUse,
+	}
+
+	result := ToOpenAIResponse(bedrockOutput, "gpt-4o")
+
+	require.Len(t, result.Choices, 1)
+	assert.Empty(t, result.Choices[0].Message.ToolCalls)
+	assert.JSONEq(t, `{"temp":21}`, result.Choices[0].Message.Content)
+	assert.Equal(t, "stop", result.Choices[0].FinishReason)
+}
+
+func TestChunkConverterStructuredOutput(t *testing.T) {
+	converter := NewChunkConverter("gpt-4o")
+
+	events := []types.ConverseStreamOutput{
+		&types.ConverseStreamOutputMemberContentBlockStart{
+			Value: types.ContentBlockStartEvent{
+				ContentBlockIndex: aws.Int32(0),
+				Start: &types.ContentBlockStartMemberToolUse{
+					Value: types.ToolUseBlockStart{ToolUseId: aws.String("tooluse_1"), Name: aws.String(structuredOutputToolName)},
+				},
+			},
+		},
+		&types.ConverseStreamOutputMemberContentBlockDelta{
+			Value: types.ContentBlockDeltaEvent{
+				ContentBlockIndex: aws.Int32(0),
+				Delta:             &types.ContentBlockDeltaMemberToolUse{Value: types.ToolUseBlockDelta{Input: aws.String(`{"temp":`)}},
+			},
+		},
+		&types.ConverseStreamOutputMemberMessageStop{
+			Value: types.MessageStopEvent{StopReason: types.StopReasonToolUse},
+		},
+	}
+
+	var chunks []openai.ChatCompletionChunk
+	for _, event := range events {
+		if chunk, ok := converter.ToOpenAIResponseChunk(event); ok {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	require.Len(t, chunks, 2)
+	assert.Empty(t, chunks[0].Choices[0].Delta.ToolCalls)
+	assert.Equal(t, `{"temp":`, chunks[0].Choices[0].Delta.Content)
+	assert.Equal(t, openai.ChatCompletionChunkChoicesFinishReason("stop"), chunks[1].Choices[0].FinishReason)
+}
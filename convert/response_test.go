@@ -174,3 +174,103 @@ func TestSetTimeProvider(t *testing.T) {
 	assert.NotEqual(t, fixedTime, newTime)
 	assert.True(t, newTime.After(originalTime))
 }
+
+func TestChunkConverterToolCalls(t *testing.T) {
+	converter := NewChunkConverter("anthropic.claude-v2")
+
+	events := []types.ConverseStreamOutput{
+		&types.ConverseStreamOutputMemberContentBlockDelta{
+			Value: types.ContentBlockDeltaEvent{
+				ContentBlockIndex: aws.Int32(0),
+				Delta:             &types.ContentBlockDeltaMemberText{Value: "Let me check."},
+			},
+		},
+		&types.ConverseStreamOutputMemberContentBlockStart{
+			Value: types.ContentBlockStartEvent{
+				ContentBlockIndex: aws.Int32(1),
+				Start: &types.ContentBlockStartMemberToolUse{
+					Value: types.ToolUseBlockStart{ToolUseId: aws.String("tooluse_1"), Name: aws.String("get_weather")},
+				},
+			},
+		},
+		&types.ConverseStreamOutputMemberContentBlockStart{
+			Value: types.ContentBlockStartEvent{
+				ContentBlockIndex: aws.Int32(2),
+				Start: &types.ContentBlockStartMemberToolUse{
+					Value: types.ToolUseBlockStart{ToolUseId: aws.String("tooluse_2"), Name: aws.String("get_time")},
+				},
+			},
+		},
+		&types.ConverseStreamOutputMemberContentBlockDelta{
+			Value: types.ContentBlockDeltaEvent{
+				ContentBlockIndex: aws.Int32(2),
+				Delta:             &types.ContentBlockDeltaMemberToolUse{Value: types.ToolUseBlockDelta{Input: aws.String(`{"tz":`)}},
+			},
+		},
+		&types.ConverseStreamOutputMemberContentBlockDelta{
+			Value: types.ContentBlockDeltaEvent{
+				ContentBlockIndex: aws.Int32(1),
+				Delta:             &types.ContentBlockDeltaMemberToolUse{Value: types.ToolUseBlockDelta{Input: aws.String(`{"city":"Paris"}`)}},
+			},
+		},
+		&types.ConverseStreamOutputMemberMessageStop{
+			Value: types.MessageStopEvent{StopReason: types.StopReasonToolUse},
+		},
+	}
+
+	var chunks []openai.ChatCompletionChunk
+	for _, event := range events {
+		if chunk, ok := converter.ToOpenAIResponseChunk(event); ok {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	assert.Equal(t, "Let me check.", chunks[0].Choices[0].Delta.Content)
+	assert.Empty(t, chunks[0].Choices[0].Delta.ToolCalls)
+
+	start := chunks[1].Choices[0].Delta.ToolCalls
+	require.Len(t, start, 1)
+	assert.Equal(t, int64(0), start[0].Index)
+	assert.Equal(t, "tooluse_1", start[0].ID)
+	assert.Equal(t, "get_weather", start[0].Function.Name)
+	assert.Equal(t, openai.ChatCompletionChunkChoicesDeltaToolCallsTypeFunction, start[0].Type)
+
+	start = chunks[2].Choices[0].Delta.ToolCalls
+	require.Len(t, start, 1)
+	assert.Equal(t, int64(1), start[0].Index)
+	assert.Equal(t, "tooluse_2", start[0].ID)
+
+	delta := chunks[3].Choices[0].Delta.ToolCalls
+	require.Len(t, delta, 1)
+	assert.Equal(t, int64(1), delta[0].Index)
+	assert.Equal(t, `{"tz":`, delta[0].Function.Arguments)
+
+	delta = chunks[4].Choices[0].Delta.ToolCalls
+	require.Len(t, delta, 1)
+	assert.Equal(t, int64(0), delta[0].Index)
+	assert.Equal(t, `{"city":"Paris"}`, delta[0].Function.Arguments)
+
+	assert.Equal(t, openai.ChatCompletionChunkChoicesFinishReason("tool_calls"), chunks[5].Choices[0].FinishReason)
+}
+
+func TestChunkConverterStableIdentity(t *testing.T) {
+	converter := NewChunkConverter("anthropic.claude-v2")
+
+	var chunks []openai.ChatCompletionChunk
+	for _, text := range []string{"Hello", " there", "!"} {
+		chunk, ok := converter.ToOpenAIResponseChunk(&types.ConverseStreamOutputMemberContentBlockDelta{
+			Value: types.ContentBlockDeltaEvent{
+				ContentBlockIndex: aws.Int32(0),
+				Delta:             &types.ContentBlockDeltaMemberText{Value: text},
+			},
+		})
+		require.True(t, ok)
+		chunks = append(chunks, chunk)
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, chunk := range chunks[1:] {
+		assert.Equal(t, chunks[0].ID, chunk.ID)
+		assert.Equal(t, chunks[0].Created, chunk.Created)
+	}
+}
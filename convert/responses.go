@@ -0,0 +1,665 @@
+package convert
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openai/openai-go"
+)
+
+// ResponsesRequest is a request to the OpenAI Responses API. It is run as a
+// chat completion built from the conversation items.
+type ResponsesRequest struct {
+	Model              string            `json:"model"`
+	Input              ResponseInput     `json:"input"`
+	Instructions       string            `json:"instructions,omitempty"`
+	PreviousResponseID string            `json:"previous_response_id,omitempty"`
+	Tools              []ResponsesTool   `json:"tools,omitempty"`
+	ToolChoice         json.RawMessage   `json:"tool_choice,omitempty"`
+	MaxOutputTokens    int               `json:"max_output_tokens,omitempty"`
+	Temperature        *float64          `json:"temperature,omitempty"`
+	TopP               *float64          `json:"top_p,omitempty"`
+	Stream             bool              `json:"stream,omitempty"`
+	Store              *bool             `json:"store,omitempty"`
+	Text               *ResponsesText    `json:"text,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+}
+
+// ResponseInput is either a plain string, which is a single user message,
+// or a list of items.
+type ResponseInput []ResponseItem
+
+func (i *ResponseInput) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*i = ResponseInput{{Type: "message", Role: "user", Content: ResponseContent{{Type: "input_text", Text: text}}}}
+		return nil
+	}
+
+	var items []ResponseItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("%w: input must be a string or an array of items", ErrInvalidContent)
+	}
+	*i = items
+	return nil
+}
+
+// ResponseItem is an input or output item: a message, a function call or a
+// function call output.
+type ResponseItem struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id,omitempty"`
+	Status    string          `json:"status,omitempty"`
+	Role      string          `json:"role,omitempty"`
+	Content   ResponseContent `json:"content,omitempty"`
+	CallID    string          `json:"call_id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Arguments string          `json:"arguments,omitempty"`
+	Output    string          `json:"output,omitempty"`
+}
+
+// UnmarshalJSON defaults the type of role-only items to message, which the
+// API allows for easy input messages.
+func (i *ResponseItem) UnmarshalJSON(data []byte) error {
+	type alias ResponseItem
+	if err := json.Unmarshal(data, (*alias)(i)); err != nil {
+		return err
+	}
+	if i.Type == "" && i.Role != "" {
+		i.Type = "message"
+	}
+	return nil
+}
+
+// MarshalJSON always includes the content of messages and the arguments of
+// function calls, which clients expect even while they are still empty.
+func (i ResponseItem) MarshalJSON() ([]byte, error) {
+	type alias ResponseItem
+	switch i.Type {
+	case "message":
+		content := i.Content
+		if content == nil {
+			content = ResponseContent{}
+		}
+		return json.Marshal(struct {
+			alias
+			Content ResponseContent `json:"content"`
+		}{alias(i), content})
+	case "function_call":
+		return json.Marshal(struct {
+			alias
+			Arguments string `json:"arguments"`
+		}{alias(i), i.Arguments})
+	default:
+		return json.Marshal(alias(i))
+	}
+}
+
+// ResponseContent is the content of a message item, which input messages
+// may send as a plain string.
+type ResponseContent []ResponseContentPart
+
+func (c *ResponseContent) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*c = ResponseContent{{Type: "input_text", Text: text}}
+		return nil
+	}
+
+	var parts []ResponseContentPart
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("%w: content must be a string or an array", ErrInvalidContent)
+	}
+	*c = parts
+	return nil
+}
+
+type ResponseContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+	FileData string `json:"file_data,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// MarshalJSON includes the text and annotations of output text, even when
+// they are empty.
+func (p ResponseContentPart) MarshalJSON() ([]byte, error) {
+	type alias ResponseContentPart
+	if p.Type != "output_text" {
+		return json.Marshal(alias(p))
+	}
+	return json.Marshal(struct {
+		Type        string        `json:"type"`
+		Text        string        `json:"text"`
+		Annotations []interface{} `json:"annotations"`
+	}{p.Type, p.Text, []interface{}{}})
+}
+
+type ResponsesTool struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Strict      *bool                  `json:"strict,omitempty"`
+}
+
+type ResponsesText struct {
+	Format *ResponsesTextFormat `json:"format,omitempty"`
+}
+
+// ResponsesTextFormat is response_format with the json_schema fields
+// flattened into it.
+type ResponsesTextFormat struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema,omitempty"`
+	Strict      *bool                  `json:"strict,omitempty"`
+}
+
+type ResponseObject struct {
+	ID                 string                     `json:"id"`
+	Object             string                     `json:"object"`
+	CreatedAt          int64                      `json:"created_at"`
+	Status             string                     `json:"status"`
+	Error              *ResponseError             `json:"error"`
+	IncompleteDetails  *ResponseIncompleteDetails `json:"incomplete_details"`
+	Instructions       *string                    `json:"instructions"`
+	MaxOutputTokens    *int                       `json:"max_output_tokens"`
+	Model              string                     `json:"model"`
+	Output             []ResponseItem             `json:"output"`
+	ParallelToolCalls  bool                       `json:"parallel_tool_calls"`
+	PreviousResponseID *string                    `json:"previous_response_id"`
+	Temperature        *float64                   `json:"temperature"`
+	TopP               *float64                   `json:"top_p"`
+	ToolChoice         json.RawMessage            `json:"tool_choice"`
+	Tools              []ResponsesTool            `json:"tools"`
+	Usage              *ResponseUsage             `json:"usage"`
+	Metadata           map[string]string          `json:"metadata"`
+	Store              bool                       `json:"store"`
+}
+
+type ResponseError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ResponseIncompleteDetails struct {
+	Reason string `json:"reason"`
+}
+
+type ResponseUsage struct {
+	InputTokens         int                        `json:"input_tokens"`
+	InputTokensDetails  ResponseInputTokensDetails `json:"input_tokens_details"`
+	OutputTokens        int                        `json:"output_tokens"`
+	OutputTokensDetails ResponseOutputTokenDetails `json:"output_tokens_details"`
+	TotalTokens         int                        `json:"total_tokens"`
+}
+
+type ResponseInputTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+type ResponseOutputTokenDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
+
+// StoredResponse is a response kept for GET /v1/responses/{id} and for
+// previous_response_id. Conversation holds every input and output item so
+// far, without the instructions, which are not carried over.
+type StoredResponse struct {
+	Response     ResponseObject
+	Conversation []ResponseItem
+}
+
+// Stored reports whether the response should be kept, which is the default.
+func (r ResponsesRequest) Stored() bool {
+	return r.Store == nil || *r.Store
+}
+
+// ToOpenAIRequest builds the chat completion for the request, continuing
+// from history, the conversation of the previous response.
+func (r ResponsesRequest) ToOpenAIRequest(history []ResponseItem) (OpenAIRequest, error) {
+	openAIReq := OpenAIRequest{
+		Model:       r.Model,
+		MaxTokens:   r.MaxOutputTokens,
+		Stream:      r.Stream,
+		Temperature: r.Temperature,
+		TopP:        r.TopP,
+	}
+
+	if r.Instructions != "" {
+		openAIReq.Messages = append(openAIReq.Messages, OpenAIMessage{Role: "system", Content: r.Instructions})
+	}
+	for _, item := range append(append([]ResponseItem{}, history...), r.Input...) {
+		msg, err := responseItemToMessage(item)
+		if err != nil {
+			return OpenAIRequest{}, err
+		}
+		openAIReq.Messages = append(openAIReq.Messages, msg)
+	}
+
+	for _, tool := range r.Tools {
+		if tool.Type != "function" {
+			return OpenAIRequest{}, fmt.Errorf("%w: unsupported tool type %q", ErrInvalidToolCall, tool.Type)
+		}
+		openAIReq.Tools = append(openAIReq.Tools, OpenAITool{
+			Type: "function",
+			Function: OpenAIFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+
+	toolChoice, err := responsesToolChoice(r.ToolChoice)
+	if err != nil {
+		return OpenAIRequest{}, err
+	}
+	openAIReq.ToolChoice = toolChoice
+
+	if r.Text != nil && r.Text.Format != nil && r.Text.Format.Type != "text" {
+		format := r.Text.Format
+		openAIReq.ResponseFormat = &OpenAIResponseFormat{Type: format.Type}
+		if format.Type == "json_schema" {
+			openAIReq.ResponseFormat.JSONSchema = &OpenAIJSONSchema{
+				Name:        format.Name,
+				Description: format.Description,
+				Schema:      format.Schema,
+				Strict:      format.Strict,
+			}
+		}
+	}
+	return openAIReq, nil
+}
+
+func responseItemToMessage(item ResponseItem) (OpenAIMessage, error) {
+	switch item.Type {
+	case "message":
+		msg := OpenAIMessage{Role: item.Role, ContentParts: []OpenAIContentPart{}}
+		for _, part := range item.Content {
+			switch part.Type {
+			case "input_text", "output_text":
+				msg.ContentParts = append(msg.ContentParts, OpenAIContentPart{Type: "text", Text: part.Text})
+			case "input_image":
+				msg.ContentParts = append(msg.ContentParts, OpenAIContentPart{
+					Type:     "image_url",
+					ImageURL: &OpenAIImageURL{URL: part.ImageURL},
+				})
+			case "input_file":
+				msg.ContentParts = append(msg.ContentParts, OpenAIContentPart{
+					Type: "file",
+					File: &OpenAIFile{Filename: part.Filename, FileData: part.FileData},
+				})
+			default:
+				return OpenAIMessage{}, fmt.Errorf("%w: unsupported content type %q", ErrInvalidContent, part.Type)
+			}
+		}
+		// Plain text messages are sent as text, which keeps system
+		// messages simple.
+		if item.Role != "user" {
+			msg.Content = msg.contentText()
+			msg.ContentParts = nil
+		}
+		return msg, nil
+	case "function_call":
+		return OpenAIMessage{
+			Role: "assistant",
+			ToolCalls: []OpenAIToolCall{{
+				ID:       item.CallID,
+				Type:     "function",
+				Function: OpenAIFunctionCall{Name: item.Name, Arguments: item.Arguments},
+			}},
+		}, nil
+	case "function_call_output":
+		return OpenAIMessage{Role: "tool", ToolCallID: item.CallID, Content: item.Output}, nil
+	default:
+		return OpenAIMessage{}, fmt.Errorf("%w: unsupported input item type %q", ErrInvalidContent, item.Type)
+	}
+}
+
+// responsesToolChoice accepts "none", "auto", "required" and
+// {"type": "function", "name": ...}.
+func responsesToolChoice(data json.RawMessage) (*OpenAIToolChoice, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	if data[0] == '"' {
+		var choice OpenAIToolChoice
+		if err := json.Unmarshal(data, &choice.Type); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidToolCall, err)
+		}
+		return &choice, nil
+	}
+
+	var obj struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToolCall, err)
+	}
+	if obj.Type != "function" {
+		return nil, fmt.Errorf("%w: unsupported tool_choice type %q", ErrInvalidToolCall, obj.Type)
+	}
+	return &OpenAIToolChoice{Type: "function", Function: obj.Name}, nil
+}
+
+// NewResponseObject returns the in-progress response for a request.
+func NewResponseObject(r ResponsesRequest) ResponseObject {
+	resp := ResponseObject{
+		ID:                newResponseID("resp"),
+		Object:            "response",
+		CreatedAt:         timeProvider().Unix(),
+		Status:            "in_progress",
+		Model:             r.Model,
+		Output:            []ResponseItem{},
+		ParallelToolCalls: true,
+		Temperature:       r.Temperature,
+		TopP:              r.TopP,
+		ToolChoice:        r.ToolChoice,
+		Tools:             r.Tools,
+		Metadata:          r.Metadata,
+		Store:             r.Stored(),
+	}
+	if resp.ToolChoice == nil {
+		resp.ToolChoice = json.RawMessage(`"auto"`)
+	}
+	if resp.Tools == nil {
+		resp.Tools = []ResponsesTool{}
+	}
+	if resp.Metadata == nil {
+		resp.Metadata = map[string]string{}
+	}
+	if r.Instructions != "" {
+		resp.Instructions = &r.Instructions
+	}
+	if r.MaxOutputTokens != 0 {
+		resp.MaxOutputTokens = &r.MaxOutputTokens
+	}
+	if r.PreviousResponseID != "" {
+		resp.PreviousResponseID = &r.PreviousResponseID
+	}
+	return resp
+}
+
+// newResponseID returns a random id. Unlike completion ids these are used
+// as store keys, so they must not collide.
+func newResponseID(prefix string) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return prefix + "_" + hex.EncodeToString(b)
+}
+
+// CompleteResponse fills in the output of resp from a buffered chat
+// completion.
+func CompleteResponse(resp ResponseObject, openAIResp OpenAIResponse) ResponseObject {
+	if len(openAIResp.Choices) > 0 {
+		choice := openAIResp.Choices[0]
+		if choice.Message.Content != "" {
+			resp.Output = append(resp.Output, ResponseItem{
+				Type:    "message",
+				ID:      newResponseID("msg"),
+				Status:  "completed",
+				Role:    "assistant",
+				Content: ResponseContent{{Type: "output_text", Text: choice.Message.Content}},
+			})
+		}
+		for _, call := range choice.Message.ToolCalls {
+			resp.Output = append(resp.Output, ResponseItem{
+				Type:      "function_call",
+				ID:        newResponseID("fc"),
+				Status:    "completed",
+				CallID:    call.ID,
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			})
+		}
+		finishResponse(&resp, choice.FinishReason)
+	} else {
+		finishResponse(&resp, "stop")
+	}
+	resp.Usage = makeResponseUsage(openAIResp.Usage)
+	return resp
+}
+
+func finishResponse(resp *ResponseObject, finishReason string) {
+	switch finishReason {
+	case "length":
+		resp.Status = "incomplete"
+		resp.IncompleteDetails = &ResponseIncompleteDetails{Reason: "max_output_tokens"}
+	case "content_filter":
+		resp.Status = "incomplete"
+		resp.IncompleteDetails = &ResponseIncompleteDetails{Reason: "content_filter"}
+	default:
+		resp.Status = "completed"
+	}
+}
+
+func makeResponseUsage(usage Usage) *ResponseUsage {
+	result := &ResponseUsage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+		TotalTokens:  usage.TotalTokens,
+	}
+	if usage.PromptTokensDetails != nil {
+		result.InputTokensDetails.CachedTokens = usage.PromptTokensDetails.CachedTokens
+	}
+	return result
+}
+
+// ResponseStreamEvent is one of the typed server-sent events of a streamed
+// response. Only the fields of its type are set.
+type ResponseStreamEvent struct {
+	Type           string               `json:"type"`
+	SequenceNumber int                  `json:"sequence_number"`
+	Response       *ResponseObject      `json:"response,omitempty"`
+	OutputIndex    *int                 `json:"output_index,omitempty"`
+	ContentIndex   *int                 `json:"content_index,omitempty"`
+	ItemID         string               `json:"item_id,omitempty"`
+	Item           *ResponseItem        `json:"item,omitempty"`
+	Part           *ResponseContentPart `json:"part,omitempty"`
+	Delta          string               `json:"delta,omitempty"`
+	Text           *string              `json:"text,omitempty"`
+	Arguments      *string              `json:"arguments,omitempty"`
+}
+
+// ResponseStream turns the chat chunks of a streamed response into
+// Responses API events, opening and closing an output item for the text and
+// for each tool call.
+type ResponseStream struct {
+	resp         ResponseObject
+	seq          int
+	started      bool
+	open         int
+	calls        map[int64]int
+	finishReason string
+}
+
+func NewResponseStream(resp ResponseObject) *ResponseStream {
+	return &ResponseStream{resp: resp, open: -1, calls: map[int64]int{}}
+}
+
+// Response returns the response as streamed so far.
+func (s *ResponseStream) Response() ResponseObject {
+	return s.resp
+}
+
+func (s *ResponseStream) event(eventType string) ResponseStreamEvent {
+	event := ResponseStreamEvent{Type: eventType, SequenceNumber: s.seq}
+	s.seq++
+	return event
+}
+
+func (s *ResponseStream) responseEvent(eventType string) ResponseStreamEvent {
+	event := s.event(eventType)
+	resp := s.resp
+	resp.Output = append([]ResponseItem{}, s.resp.Output...)
+	event.Response = &resp
+	return event
+}
+
+func (s *ResponseStream) itemEvent(eventType string, index int) ResponseStreamEvent {
+	event := s.event(eventType)
+	item := s.resp.Output[index]
+	event.OutputIndex = &index
+	event.Item = &item
+	return event
+}
+
+func (s *ResponseStream) start() []ResponseStreamEvent {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	return []ResponseStreamEvent{s.responseEvent("response.created"), s.responseEvent("response.in_progress")}
+}
+
+// Chunk converts one chat chunk into the events it causes.
+func (s *ResponseStream) Chunk(chunk openai.ChatCompletionChunk) []ResponseStreamEvent {
+	events := s.start()
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" {
+			events = append(events, s.text(choice.Delta.Content)...)
+		}
+		for _, call := range choice.Delta.ToolCalls {
+			events = append(events, s.toolCall(call)...)
+		}
+		if choice.FinishReason != "" {
+			s.finishReason = string(choice.FinishReason)
+		}
+	}
+	return events
+}
+
+func (s *ResponseStream) text(delta string) []ResponseStreamEvent {
+	var events []ResponseStreamEvent
+	if s.open < 0 || s.resp.Output[s.open].Type != "message" {
+		events = append(events, s.closeItem()...)
+		s.open = len(s.resp.Output)
+		s.resp.Output = append(s.resp.Output, ResponseItem{
+			Type:   "message",
+			ID:     newResponseID("msg"),
+			Status: "in_progress",
+			Role:   "assistant",
+		})
+		events = append(events, s.itemEvent("response.output_item.added", s.open))
+
+		s.resp.Output[s.open].Content = ResponseContent{{Type: "output_text"}}
+		event := s.contentEvent(s.open, "response.content_part.added")
+		event.Part = &ResponseContentPart{Type: "output_text"}
+		events = append(events, event)
+	}
+
+	s.resp.Output[s.open].Content[0].Text += delta
+	event := s.contentEvent(s.open, "response.output_text.delta")
+	event.Delta = delta
+	return append(events, event)
+}
+
+func (s *ResponseStream) contentEvent(index int, eventType string) ResponseStreamEvent {
+	event := s.event(eventType)
+	contentIndex := 0
+	event.OutputIndex = &index
+	event.ContentIndex = &contentIndex
+	event.ItemID = s.resp.Output[index].ID
+	return event
+}
+
+func (s *ResponseStream) toolCall(call openai.ChatCompletionChunkChoicesDeltaToolCall) []ResponseStreamEvent {
+	var events []ResponseStreamEvent
+	index, ok := s.calls[call.Index]
+	if !ok {
+		events = append(events, s.closeItem()...)
+		index = len(s.resp.Output)
+		s.calls[call.Index] = index
+		s.open = index
+		s.resp.Output = append(s.resp.Output, ResponseItem{
+			Type:   "function_call",
+			ID:     newResponseID("fc"),
+			Status: "in_progress",
+			CallID: call.ID,
+			Name:   call.Function.Name,
+		})
+		events = append(events, s.itemEvent("response.output_item.added", index))
+	}
+
+	if call.Function.Arguments != "" {
+		s.resp.Output[index].Arguments += call.Function.Arguments
+		event := s.event("response.function_call_arguments.delta")
+		event.OutputIndex = &index
+		event.ItemID = s.resp.Output[index].ID
+		event.Delta = call.Function.Arguments
+		events = append(events, event)
+	}
+	return events
+}
+
+// closeItem finishes the output item being streamed, if any.
+func (s *ResponseStream) closeItem() []ResponseStreamEvent {
+	if s.open < 0 {
+		return nil
+	}
+	index := s.open
+	s.open = -1
+	item := &s.resp.Output[index]
+
+	var events []ResponseStreamEvent
+	switch item.Type {
+	case "message":
+		text := item.Content[0].Text
+		event := s.contentEvent(index, "response.output_text.done")
+		event.Text = &text
+		events = append(events, event)
+
+		event = s.contentEvent(index, "response.content_part.done")
+		part := item.Content[0]
+		event.Part = &part
+		events = append(events, event)
+	case "function_call":
+		arguments := item.Arguments
+		event := s.event("response.function_call_arguments.done")
+		event.OutputIndex = &index
+		event.ItemID = item.ID
+		event.Arguments = &arguments
+		events = append(events, event)
+	}
+
+	item.Status = "completed"
+	return append(events, s.itemEvent("response.output_item.done", index))
+}
+
+// Done closes the last output item and reports the outcome of the response.
+func (s *ResponseStream) Done(usage Usage) []ResponseStreamEvent {
+	events := append(s.start(), s.closeItem()...)
+	finishResponse(&s.resp, s.finishReason)
+	s.resp.Usage = makeResponseUsage(usage)
+	if s.resp.Status == "incomplete" {
+		return append(events, s.responseEvent("response.incomplete"))
+	}
+	return append(events, s.responseEvent("response.completed"))
+}
+
+// Fail reports a response that ended with an error.
+func (s *ResponseStream) Fail(code, message string) []ResponseStreamEvent {
+	events := s.start()
+	s.resp.Status = "failed"
+	s.resp.Error = &ResponseError{Code: code, Message: message}
+	return append(events, s.responseEvent("response.failed"))
+}
@@ -0,0 +1,150 @@
+package convert
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponsesRequestToOpenAIRequest(t *testing.T) {
+	var req ResponsesRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "gpt-4o",
+		"instructions": "Be brief.",
+		"input": [
+			{"role": "user", "content": [
+				{"type": "input_text", "text": "What is in this image?"},
+				{"type": "input_image", "image_url": "data:image/png;base64,AAAA"}
+			]},
+			{"type": "function_call", "call_id": "call_1", "name": "lookup", "arguments": "{}"},
+			{"type": "function_call_output", "call_id": "call_1", "output": "a cat"}
+		],
+		"tools": [{"type": "function", "name": "lookup", "parameters": {"type": "object"}}],
+		"tool_choice": {"type": "function", "name": "lookup"},
+		"max_output_tokens": 100,
+		"text": {"format": {"type": "json_schema", "name": "answer", "schema": {"type": "object"}}}
+	}`), &req))
+
+	history := []ResponseItem{
+		{Type: "message", Role: "user", Content: ResponseContent{{Type: "input_text", Text: "Hi"}}},
+		{Type: "message", Role: "assistant", Content: ResponseContent{{Type: "output_text", Text: "Hello"}}},
+	}
+	openAIReq, err := req.ToOpenAIRequest(history)
+	require.NoError(t, err)
+
+	assert.Equal(t, 100, openAIReq.MaxTokens)
+	assert.Equal(t, []OpenAIMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", ContentParts: []OpenAIContentPart{{Type: "text", Text: "Hi"}}},
+		{Role: "assistant", Content: "Hello"},
+		{Role: "user", ContentParts: []OpenAIContentPart{
+			{Type: "text", Text: "What is in this image?"},
+			{Type: "image_url", ImageURL: &OpenAIImageURL{URL: "data:image/png;base64,AAAA"}},
+		}},
+		{Role: "assistant", ToolCalls: []OpenAIToolCall{{ID: "call_1", Type: "function", Function: OpenAIFunctionCall{Name: "lookup", Arguments: "{}"}}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "a cat"},
+	}, openAIReq.Messages)
+	require.Len(t, openAIReq.Tools, 1)
+	assert.Equal(t, "lookup", openAIReq.Tools[0].Function.Name)
+	assert.Equal(t, &OpenAIToolChoice{Type: "function", Function: "lookup"}, openAIReq.ToolChoice)
+	require.NotNil(t, openAIReq.ResponseFormat)
+	assert.Equal(t, "json_schema", openAIReq.ResponseFormat.Type)
+	assert.Equal(t, "answer", openAIReq.ResponseFormat.JSONSchema.Name)
+
+	var simple ResponsesRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"model": "gpt-4o", "input": "Hi"}`), &simple))
+	openAIReq, err = simple.ToOpenAIRequest(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []OpenAIMessage{{Role: "user", ContentParts: []OpenAIContentPart{{Type: "text", Text: "Hi"}}}}, openAIReq.Messages)
+
+	_, err = ResponsesRequest{Tools: []ResponsesTool{{Type: "web_search_preview"}}}.ToOpenAIRequest(nil)
+	assert.ErrorIs(t, err, ErrInvalidToolCall)
+}
+
+func TestCompleteResponse(t *testing.T) {
+	resp := CompleteResponse(NewResponseObject(ResponsesRequest{Model: "gpt-4o", MaxOutputTokens: 5}), OpenAIResponse{
+		Choices: []Choice{{
+			Message: OpenAIMessage{
+				Role:      "assistant",
+				Content:   "Let me check.",
+				ToolCalls: []OpenAIToolCall{{ID: "call_1", Type: "function", Function: OpenAIFunctionCall{Name: "lookup", Arguments: `{"q":"x"}`}}},
+			},
+			FinishReason: "length",
+		}},
+		Usage: Usage{PromptTokens: 3, CompletionTokens: 5, TotalTokens: 8},
+	})
+
+	assert.Equal(t, "response", resp.Object)
+	assert.Equal(t, "incomplete", resp.Status)
+	assert.Equal(t, &ResponseIncompleteDetails{Reason: "max_output_tokens"}, resp.IncompleteDetails)
+	require.Len(t, resp.Output, 2)
+	assert.Equal(t, "message", resp.Output[0].Type)
+	assert.Equal(t, "Let me check.", resp.Output[0].Content[0].Text)
+	assert.Equal(t, "function_call", resp.Output[1].Type)
+	assert.Equal(t, "call_1", resp.Output[1].CallID)
+	assert.Equal(t, 8, resp.Usage.TotalTokens)
+
+	bytes, err := json.Marshal(resp.Output[0].Content[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "output_text", "text": "Let me check.", "annotations": []}`, string(bytes))
+}
+
+func TestResponseStream(t *testing.T) {
+	stream := NewResponseStream(NewResponseObject(ResponsesRequest{Model: "gpt-4o"}))
+
+	chunk := func(delta openai.ChatCompletionChunkChoicesDelta, finishReason openai.ChatCompletionChunkChoicesFinishReason) openai.ChatCompletionChunk {
+		return openai.ChatCompletionChunk{Choices: []openai.ChatCompletionChunkChoice{{Delta: delta, FinishReason: finishReason}}}
+	}
+
+	var events []ResponseStreamEvent
+	for _, c := range []openai.ChatCompletionChunk{
+		chunk(openai.ChatCompletionChunkChoicesDelta{Role: "assistant"}, ""),
+		chunk(openai.ChatCompletionChunkChoicesDelta{Content: "Hel"}, ""),
+		chunk(openai.ChatCompletionChunkChoicesDelta{Content: "lo"}, ""),
+		chunk(openai.ChatCompletionChunkChoicesDelta{ToolCalls: []openai.ChatCompletionChunkChoicesDeltaToolCall{
+			{Index: 0, ID: "call_1", Function: openai.ChatCompletionChunkChoicesDeltaToolCallsFunction{Name: "lookup"}},
+		}}, ""),
+		chunk(openai.ChatCompletionChunkChoicesDelta{ToolCalls: []openai.ChatCompletionChunkChoicesDeltaToolCall{
+			{Index: 0, Function: openai.ChatCompletionChunkChoicesDeltaToolCallsFunction{Arguments: "{}"}},
+		}}, ""),
+		chunk(openai.ChatCompletionChunkChoicesDelta{}, "tool_calls"),
+	} {
+		events = append(events, stream.Chunk(c)...)
+	}
+	events = append(events, stream.Done(Usage{TotalTokens: 3})...)
+
+	var types []string
+	for i, event := range events {
+		assert.Equal(t, i, event.SequenceNumber)
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{
+		"response.created",
+		"response.in_progress",
+		"response.output_item.added",
+		"response.content_part.added",
+		"response.output_text.delta",
+		"response.output_text.delta",
+		"response.output_text.done",
+		"response.content_part.done",
+		"response.output_item.done",
+		"response.output_item.added",
+		"response.function_call_arguments.delta",
+		"response.function_call_arguments.done",
+		"response.output_item.done",
+		"response.completed",
+	}, types)
+
+	assert.Equal(t, "Hello", *events[6].Text)
+	assert.Equal(t, "{}", *events[11].Arguments)
+
+	resp := events[len(events)-1].Response
+	assert.Equal(t, "completed", resp.Status)
+	require.Len(t, resp.Output, 2)
+	assert.Equal(t, "completed", resp.Output[0].Status)
+	assert.Equal(t, "lookup", resp.Output[1].Name)
+	assert.Equal(t, 3, resp.Usage.TotalTokens)
+}
@@ -0,0 +1,258 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+type OpenAITool struct {
+	Type     string         `json:"type"`
+	Function OpenAIFunction `json:"function"`
+}
+
+type OpenAIFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type OpenAIToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function OpenAIFunctionCall `json:"function"`
+}
+
+type OpenAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// OpenAIToolChoice accepts both the string form ("none", "auto",
+// "required") and the object form ({"type":"function","function":{...}}).
+type OpenAIToolChoice struct {
+	Type     string
+	Function string
+}
+
+func (c *OpenAIToolChoice) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &c.Type)
+	}
+
+	var obj struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	c.Type = obj.Type
+	c.Function = obj.Function.Name
+	return nil
+}
+
+func (c OpenAIToolChoice) MarshalJSON() ([]byte, error) {
+	if c.Function == "" {
+		return json.Marshal(c.Type)
+	}
+	obj := map[string]interface{}{
+		"type":     "function",
+		"function": map[string]string{"name": c.Function},
+	}
+	return json.Marshal(obj)
+}
+
+var ErrInvalidToolCall = errors.New("invalid tool call")
+
+func makeToolConfig(openAIReq OpenAIRequest) (*types.ToolConfiguration, error) {
+	tools := make([]types.Tool, 0, len(openAIReq.Tools)+1)
+	var toolChoice types.ToolChoice
+
+	// Bedrock has no equivalent of "none", so the tools are simply not
+	// offered, and earlier tool calls are sent as text, see conversation.
+	if len(openAIReq.Tools) > 0 && !openAIReq.toolChoiceNone() {
+		for _, tool := range openAIReq.Tools {
+			spec, err := makeToolSpec(tool)
+			if err != nil {
+				return nil, err
+			}
+			tools = append(tools, spec)
+		}
+
+		var err error
+		toolChoice, err = makeToolChoice(openAIReq.ToolChoice)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := openAIReq.ResponseFormat.check(); err != nil {
+		return nil, err
+	}
+	if openAIReq.ResponseFormat.structured() {
+		tool, err := makeStructuredOutputTool(openAIReq.ResponseFormat)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, tool)
+		toolChoice = structuredOutputToolChoice(openAIReq, toolChoice)
+	}
+
+	if len(tools) == 0 {
+		return nil, nil
+	}
+
+	return &types.ToolConfiguration{
+		Tools:      tools,
+		ToolChoice: toolChoice,
+	}, nil
+}
+
+func (r OpenAIRequest) toolChoiceNone() bool {
+	return r.ToolChoice != nil && r.ToolChoice.Type == "none"
+}
+
+// conversation returns the messages to send. Bedrock rejects tool calls
+// and results in a conversation without tools, so when tool_choice is
+// "none" they are written out as text instead.
+func (r OpenAIRequest) conversation() []OpenAIMessage {
+	if !r.toolChoiceNone() {
+		return r.Messages
+	}
+
+	messages := make([]OpenAIMessage, 0, len(r.Messages))
+	for _, msg := range r.Messages {
+		switch {
+		case msg.Role == "tool":
+			messages = append(messages, OpenAIMessage{
+				Role:    "user",
+				Content: fmt.Sprintf("Result of tool call %s: %s", msg.ToolCallID, msg.contentText()),
+			})
+		case len(msg.ToolCalls) > 0:
+			texts := []string{msg.contentText()}
+			for _, call := range msg.ToolCalls {
+				texts = append(texts, fmt.Sprintf("Called tool %s (call %s) with %s", call.Function.Name, call.ID, call.Function.Arguments))
+			}
+			messages = append(messages, OpenAIMessage{
+				Role:      msg.Role,
+				Content:   strings.TrimSpace(strings.Join(texts, "\n")),
+				Reasoning: msg.Reasoning,
+			})
+		default:
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+func makeToolSpec(tool OpenAITool) (types.Tool, error) {
+	if tool.Type != "" && tool.Type != "function" {
+		return nil, fmt.Errorf("%w: unsupported tool type %q", ErrInvalidToolCall, tool.Type)
+	}
+	if tool.Function.Name == "" {
+		return nil, fmt.Errorf("%w: tool function name is required", ErrInvalidToolCall)
+	}
+	if tool.Function.Name == structuredOutputToolName {
+		return nil, fmt.Errorf("%w: tool name %q is reserved", ErrInvalidToolCall, structuredOutputToolName)
+	}
+
+	parameters := tool.Function.Parameters
+	if parameters == nil {
+		parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+
+	spec := types.ToolSpecification{
+		Name: aws.String(tool.Function.Name),
+		InputSchema: &types.ToolInputSchemaMemberJson{
+			Value: document.NewLazyDocument(parameters),
+		},
+	}
+	if tool.Function.Description != "" {
+		spec.Description = aws.String(tool.Function.Description)
+	}
+	return &types.ToolMemberToolSpec{Value: spec}, nil
+}
+
+func makeToolChoice(choice *OpenAIToolChoice) (types.ToolChoice, error) {
+	if choice == nil {
+		return nil, nil
+	}
+
+	switch choice.Type {
+	case "", "auto":
+		return &types.ToolChoiceMemberAuto{}, nil
+	case "required":
+		return &types.ToolChoiceMemberAny{}, nil
+	case "function":
+		if choice.Function == "" {
+			return nil, fmt.Errorf("%w: tool_choice function name is required", ErrInvalidToolCall)
+		}
+		return &types.ToolChoiceMemberTool{
+			Value: types.SpecificToolChoice{Name: aws.String(choice.Function)},
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported tool_choice %q", ErrInvalidToolCall, choice.Type)
+	}
+}
+
+func makeToolUseBlock(toolCall OpenAIToolCall) (types.ContentBlock, error) {
+	var input interface{} = map[string]interface{}{}
+	if toolCall.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &input); err != nil {
+			return nil, fmt.Errorf("%w: arguments of tool call %q are not valid JSON: %w", ErrInvalidToolCall, toolCall.ID, err)
+		}
+	}
+
+	return &types.ContentBlockMemberToolUse{
+		Value: types.ToolUseBlock{
+			ToolUseId: aws.String(toolCall.ID),
+			Name:      aws.String(toolCall.Function.Name),
+			Input:     document.NewLazyDocument(input),
+		},
+	}, nil
+}
+
+func makeToolResultBlock(msg OpenAIMessage) (types.ContentBlock, error) {
+	if msg.ToolCallID == "" {
+		return nil, fmt.Errorf("%w: tool message is missing tool_call_id", ErrInvalidToolCall)
+	}
+
+	return &types.ContentBlockMemberToolResult{
+		Value: types.ToolResultBlock{
+			ToolUseId: aws.String(msg.ToolCallID),
+			Content: []types.ToolResultContentBlock{
+				&types.ToolResultContentBlockMemberText{
+					Value: msg.contentText(),
+				},
+			},
+		},
+	}, nil
+}
+
+func toOpenAIToolCall(toolUse types.ToolUseBlock) OpenAIToolCall {
+	arguments := "{}"
+	if toolUse.Input != nil {
+		if data, err := toolUse.Input.MarshalSmithyDocument(); err == nil {
+			arguments = string(data)
+		}
+	}
+
+	return OpenAIToolCall{
+		ID:   aws.ToString(toolUse.ToolUseId),
+		Type: "function",
+		Function: OpenAIFunctionCall{
+			Name:      aws.ToString(toolUse.Name),
+			Arguments: arguments,
+		},
+	}
+}
@@ -0,0 +1,227 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIToolChoiceUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected OpenAIToolChoice
+	}{
+		{
+			name:     "string form",
+			input:    `"required"`,
+			expected: OpenAIToolChoice{Type: "required"},
+		},
+		{
+			name:     "object form",
+			input:    `{"type":"function","function":{"name":"get_weather"}}`,
+			expected: OpenAIToolChoice{Type: "function", Function: "get_weather"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var choice OpenAIToolChoice
+			require.NoError(t, json.Unmarshal([]byte(tt.input), &choice))
+			assert.Equal(t, tt.expected, choice)
+		})
+	}
+}
+
+func TestToBedrockRequestTools(t *testing.T) {
+	input := OpenAIRequest{
+		Model: "anthropic.claude-v2",
+		Tools: []OpenAITool{
+			{
+				Type: "function",
+				Function: OpenAIFunction{
+					Name:        "get_weather",
+					Description: "Get the weather",
+					Parameters: map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"city": map[string]interface{}{"type": "string"},
+						},
+					},
+				},
+			},
+		},
+		ToolChoice: &OpenAIToolChoice{Type: "function", Function: "get_weather"},
+		Messages: []OpenAIMessage{
+			{Role: "user", Content: "What is the weather in Paris and Rome?"},
+			{
+				Role: "assistant",
+				ToolCalls: []OpenAIToolCall{
+					{ID: "call_1", Type: "function", Function: OpenAIFunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+					{ID: "call_2", Type: "function", Function: OpenAIFunctionCall{Name: "get_weather", Arguments: `{"city":"Rome"}`}},
+				},
+			},
+			{Role: "tool", ToolCallID: "call_1", Content: "sunny"},
+			{Role: "tool", ToolCallID: "call_2", Content: "rainy"},
+		},
+	}
+
+	result, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, input)
+	require.NoError(t, err)
+
+	require.NotNil(t, result.ToolConfig)
+	require.Len(t, result.ToolConfig.Tools, 1)
+	spec := result.ToolConfig.Tools[0].(*types.ToolMemberToolSpec).Value
+	assert.Equal(t, "get_weather", aws.ToString(spec.Name))
+	assert.Equal(t, "Get the weather", aws.ToString(spec.Description))
+	assert.Equal(t, &types.ToolChoiceMemberTool{
+		Value: types.SpecificToolChoice{Name: aws.String("get_weather")},
+	}, result.ToolConfig.ToolChoice)
+
+	require.Len(t, result.Messages, 3)
+
+	assistant := result.Messages[1]
+	assert.Equal(t, types.ConversationRoleAssistant, assistant.Role)
+	require.Len(t, assistant.Content, 2)
+	toolUse := assistant.Content[0].(*types.ContentBlockMemberToolUse).Value
+	assert.Equal(t, "call_1", aws.ToString(toolUse.ToolUseId))
+	assert.Equal(t, "get_weather", aws.ToString(toolUse.Name))
+
+	toolResults := result.Messages[2]
+	assert.Equal(t, types.ConversationRoleUser, toolResults.Role)
+	require.Len(t, toolResults.Content, 2)
+	for i, id := range []string{"call_1", "call_2"} {
+		toolResult := toolResults.Content[i].(*types.ContentBlockMemberToolResult).Value
+		assert.Equal(t, id, aws.ToString(toolResult.ToolUseId))
+	}
+}
+
+func TestToBedrockRequestToolChoiceNone(t *testing.T) {
+	input := OpenAIRequest{
+		Model:      "anthropic.claude-v2",
+		Tools:      []OpenAITool{{Type: "function", Function: OpenAIFunction{Name: "get_weather"}}},
+		ToolChoice: &OpenAIToolChoice{Type: "none"},
+		Messages:   []OpenAIMessage{{Role: "user", Content: "Hello"}},
+	}
+
+	result, err := ToBedrockStreamRequest(context.Background(), bedrock.ModelMap{}, input)
+	require.NoError(t, err)
+	assert.Nil(t, result.ToolConfig)
+}
+
+func TestToBedrockRequestToolChoiceNoneAfterToolRound(t *testing.T) {
+	input := OpenAIRequest{
+		Model:      "anthropic.claude-v2",
+		Tools:      []OpenAITool{{Type: "function", Function: OpenAIFunction{Name: "get_weather"}}},
+		ToolChoice: &OpenAIToolChoice{Type: "none"},
+		Messages: []OpenAIMessage{
+			{Role: "user", Content: "What is the weather in Paris?"},
+			{
+				Role: "assistant",
+				ToolCalls: []OpenAIToolCall{
+					{ID: "call_1", Type: "function", Function: OpenAIFunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+				},
+			},
+			{Role: "tool", ToolCallID: "call_1", Content: "sunny"},
+		},
+	}
+
+	result, err := ToBedrockStreamRequest(context.Background(), bedrock.ModelMap{}, input)
+	require.NoError(t, err)
+	assert.Nil(t, result.ToolConfig)
+
+	// Without tools the model cannot return tool calls, and Bedrock only
+	// accepts the history once its tool blocks are text.
+	require.Len(t, result.Messages, 3)
+	for _, msg := range result.Messages {
+		for _, block := range msg.Content {
+			assert.IsType(t, &types.ContentBlockMemberText{}, block)
+		}
+	}
+	assert.Equal(t, `Called tool get_weather (call call_1) with {"city":"Paris"}`,
+		result.Messages[1].Content[0].(*types.ContentBlockMemberText).Value)
+	assert.Equal(t, "Result of tool call call_1: sunny",
+		result.Messages[2].Content[0].(*types.ContentBlockMemberText).Value)
+}
+
+func TestToBedrockRequestInvalidToolCall(t *testing.T) {
+	tests := []struct {
+		name  string
+		input OpenAIRequest
+	}{
+		{
+			name: "invalid arguments",
+			input: OpenAIRequest{
+				Messages: []OpenAIMessage{
+					{
+						Role: "assistant",
+						ToolCalls: []OpenAIToolCall{
+							{ID: "call_1", Function: OpenAIFunctionCall{Name: "f", Arguments: "{"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "missing tool_call_id",
+			input: OpenAIRequest{
+				Messages: []OpenAIMessage{{Role: "tool", Content: "result"}},
+			},
+		},
+		{
+			name: "unsupported tool_choice",
+			input: OpenAIRequest{
+				Tools:      []OpenAITool{{Type: "function", Function: OpenAIFunction{Name: "f"}}},
+				ToolChoice: &OpenAIToolChoice{Type: "sometimes"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ToBedrockRequest(context.Background(), bedrock.ModelMap{}, tt.input)
+			assert.ErrorIs(t, err, ErrInvalidToolCall)
+		})
+	}
+}
+
+func TestToOpenAIResponseToolCalls(t *testing.T) {
+	bedrockOutput := &bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{
+				Role: types.ConversationRoleAssistant,
+				Content: []types.ContentBlock{
+					&types.ContentBlockMemberText{Value: "Let me check."},
+					&types.ContentBlockMemberToolUse{
+						Value: types.ToolUseBlock{
+							ToolUseId: aws.String("tooluse_1"),
+							Name:      aws.String("get_weather"),
+							Input:     document.NewLazyDocument(map[string]interface{}{"city": "Paris"}),
+						},
+					},
+				},
+			},
+		},
+		StopReason: types.StopReasonToolUse,
+	}
+
+	result := ToOpenAIResponse(bedrockOutput, "anthropic.claude-v2")
+
+	require.Len(t, result.Choices, 1)
+	assert.Equal(t, "tool_calls", result.Choices[0].FinishReason)
+	assert.Equal(t, "Let me check.", result.Choices[0].Message.Content)
+	require.Len(t, result.Choices[0].Message.ToolCalls, 1)
+	toolCall := result.Choices[0].Message.ToolCalls[0]
+	assert.Equal(t, "tooluse_1", toolCall.ID)
+	assert.Equal(t, "function", toolCall.Type)
+	assert.Equal(t, "get_weather", toolCall.Function.Name)
+	assert.JSONEq(t, `{"city":"Paris"}`, toolCall.Function.Arguments)
+}
@@ -0,0 +1,90 @@
+package convert
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/openai/openai-go"
+)
+
+type Usage struct {
+	PromptTokens        int                  `json:"prompt_tokens"`
+	CompletionTokens    int                  `json:"completion_tokens"`
+	TotalTokens         int                  `json:"total_tokens"`
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+}
+
+type PromptTokensDetails struct {
+	CachedTokens     int `json:"cached_tokens"`
+	CacheWriteTokens int `json:"cache_write_tokens"`
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// makeUsage converts Bedrock token counts. Bedrock reports cache reads and
+// writes separately from the input tokens, while OpenAI counts them as part
+// of the prompt.
+func makeUsage(usage *types.TokenUsage) Usage {
+	if usage == nil {
+		return Usage{}
+	}
+
+	cacheRead := int(aws.ToInt32(usage.CacheReadInputTokens))
+	cacheWrite := int(aws.ToInt32(usage.CacheWriteInputTokens))
+	result := Usage{
+		PromptTokens:     int(aws.ToInt32(usage.InputTokens)) + cacheRead + cacheWrite,
+		CompletionTokens: int(aws.ToInt32(usage.OutputTokens)),
+	}
+	result.TotalTokens = result.PromptTokens + result.CompletionTokens
+	if cacheRead > 0 || cacheWrite > 0 {
+		result.PromptTokensDetails = &PromptTokensDetails{
+			CachedTokens:     cacheRead,
+			CacheWriteTokens: cacheWrite,
+		}
+	}
+	return result
+}
+
+func (u Usage) Add(other Usage) Usage {
+	sum := Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+	}
+	if u.PromptTokensDetails != nil || other.PromptTokensDetails != nil {
+		sum.PromptTokensDetails = &PromptTokensDetails{}
+		for _, details := range []*PromptTokensDetails{u.PromptTokensDetails, other.PromptTokensDetails} {
+			if details != nil {
+				sum.PromptTokensDetails.CachedTokens += details.CachedTokens
+				sum.PromptTokensDetails.CacheWriteTokens += details.CacheWriteTokens
+			}
+		}
+	}
+	return sum
+}
+
+func (u Usage) toOpenAI() openai.CompletionUsage {
+	usage := openai.CompletionUsage{
+		PromptTokens:     int64(u.PromptTokens),
+		CompletionTokens: int64(u.CompletionTokens),
+		TotalTokens:      int64(u.TotalTokens),
+	}
+	if u.PromptTokensDetails != nil {
+		usage.PromptTokensDetails.CachedTokens = int64(u.PromptTokensDetails.CachedTokens)
+	}
+	return usage
+}
+
+// ToOpenAIUsageChunk builds the final, choice-less chunk sent when the
+// client asked for stream_options.include_usage.
+func ToOpenAIUsageChunk(identity StreamIdentity, usage Usage, model string) openai.ChatCompletionChunk {
+	return openai.ChatCompletionChunk{
+		ID:      identity.ID,
+		Object:  "chat.completion.chunk",
+		Created: identity.Created,
+		Model:   model,
+		Choices: []openai.ChatCompletionChunkChoice{},
+		Usage:   usage.toOpenAI(),
+	}
+}
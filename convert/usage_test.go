@@ -0,0 +1,102 @@
+package convert
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToOpenAIResponseUsage(t *testing.T) {
+	bedrockOutput := &bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{
+				Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "Hi"}},
+			},
+		},
+		StopReason: types.StopReasonEndTurn,
+		Usage: &types.TokenUsage{
+			InputTokens:           aws.Int32(10),
+			OutputTokens:          aws.Int32(5),
+			TotalTokens:           aws.Int32(135),
+			CacheReadInputTokens:  aws.Int32(100),
+			CacheWriteInputTokens: aws.Int32(20),
+		},
+	}
+
+	result := ToOpenAIResponse(bedrockOutput, "anthropic.claude-v2")
+
+	bytes, err := json.Marshal(result.Usage)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"prompt_tokens": 130,
+		"completion_tokens": 5,
+		"total_tokens": 135,
+		"prompt_tokens_details": {
+			"cached_tokens": 100,
+			"cache_write_tokens": 20
+		}
+	}`, string(bytes))
+}
+
+func TestMergeOpenAIResponsesUsage(t *testing.T) {
+	responses := []OpenAIResponse{
+		{Choices: []Choice{{}}, Usage: Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}},
+		{Choices: []Choice{{}}, Usage: Usage{
+			PromptTokens: 10, CompletionTokens: 7, TotalTokens: 17,
+			PromptTokensDetails: &PromptTokensDetails{CachedTokens: 8},
+		}},
+	}
+
+	result := MergeOpenAIResponses(responses)
+
+	assert.Equal(t, Usage{
+		PromptTokens: 20, CompletionTokens: 12, TotalTokens: 32,
+		PromptTokensDetails: &PromptTokensDetails{CachedTokens: 8},
+	}, result.Usage)
+	require.Len(t, result.Choices, 2)
+	assert.Equal(t, 1, result.Choices[1].Index)
+}
+
+func TestChunkConverterUsage(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	oldProvider := SetTimeProvider(func() time.Time {
+		return fixedTime
+	})
+	defer SetTimeProvider(oldProvider)
+
+	identity := NewStreamIdentity()
+	converter := NewChunkConverterWithIdentity(identity, "anthropic.claude-v2")
+
+	_, ok := converter.ToOpenAIResponseChunk(&types.ConverseStreamOutputMemberMetadata{
+		Value: types.ConverseStreamMetadataEvent{
+			Usage: &types.TokenUsage{
+				InputTokens:          aws.Int32(10),
+				OutputTokens:         aws.Int32(5),
+				CacheReadInputTokens: aws.Int32(4),
+			},
+		},
+	})
+	assert.False(t, ok)
+	assert.Equal(t, Usage{
+		PromptTokens: 14, CompletionTokens: 5, TotalTokens: 19,
+		PromptTokensDetails: &PromptTokensDetails{CachedTokens: 4},
+	}, converter.Usage())
+
+	chunk := ToOpenAIUsageChunk(identity, converter.Usage(), "anthropic.claude-v2")
+	assert.Equal(t, identity.ID, chunk.ID)
+	assert.Empty(t, chunk.Choices)
+	assert.Equal(t, int64(14), chunk.Usage.PromptTokens)
+	assert.Equal(t, int64(5), chunk.Usage.CompletionTokens)
+	assert.Equal(t, int64(19), chunk.Usage.TotalTokens)
+	assert.Equal(t, int64(4), chunk.Usage.PromptTokensDetails.CachedTokens)
+
+	bytes, err := json.Marshal(chunk)
+	require.NoError(t, err)
+	assert.Contains(t, string(bytes), `"choices":[]`)
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/DefangLabs/bedrock-sidecar/convert"
+	"github.com/openai/openai-go"
+)
+
+type AnthropicErrorResponse struct {
+	Type  string         `json:"type"`
+	Error AnthropicError `json:"error"`
+}
+
+type AnthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func writeAnthropicError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	body := AnthropicErrorResponse{Type: "error", Error: AnthropicError{Type: anthropicErrorType(status), Message: message}}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("Failed to encode error response", "error", err)
+	}
+}
+
+func writeAnthropicBedrockError(w http.ResponseWriter, err error) {
+	status, _, _ := classifyBedrockError(err)
+	writeAnthropicError(w, status, err.Error())
+}
+
+func writeAnthropicConversionError(w http.ResponseWriter, err error) {
+	writeAnthropicError(w, conversionErrorStatus(err), err.Error())
+}
+
+// anthropicErrorType returns the Anthropic error type that SDKs expect for
+// an HTTP status.
+func anthropicErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusMethodNotAllowed:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// HandleAnthropicMessages serves the Anthropic Messages API by running the
+// conversation as a chat completion.
+func (h Handler) HandleAnthropicMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAnthropicError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx := r.Context()
+	var anthropicReq convert.AnthropicRequest
+	if err := json.NewDecoder(r.Body).Decode(&anthropicReq); err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	slog.Debug("Received", "request", anthropicReq)
+
+	openAIReq, err := anthropicReq.ToOpenAIRequest()
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if openAIReq.Stream {
+		h.handleStreamedChatCompletion(ctx, w, openAIReq, anthropicEncoder(convert.NewAnthropicStream(anthropicReq.Model)))
+		return
+	}
+
+	bedrockReq, err := convert.ToBedrockRequest(ctx, h.ModelMap, openAIReq)
+	if err != nil {
+		writeAnthropicConversionError(w, err)
+		return
+	}
+	slog.Debug("Converted", "request", bedrockReq)
+
+	openAIResp, err := h.converse(ctx, bedrockReq, openAIReq)
+	if err != nil {
+		slog.Error("Failed to invoke Bedrock Converse", "error", err)
+		writeAnthropicBedrockError(w, err)
+		return
+	}
+
+	writeJSON(w, convert.ToAnthropicResponse(openAIResp, anthropicReq.Model))
+}
+
+func anthropicEncoder(stream *convert.AnthropicStream) chunkEncoder {
+	writeEvents := func(sse *sseWriter, events []convert.AnthropicStreamEvent) error {
+		for _, event := range events {
+			if err := sse.writeEvent(event.Type, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return chunkEncoder{
+		chunk: func(sse *sseWriter, chunk openai.ChatCompletionChunk) error {
+			return writeEvents(sse, stream.Chunk(chunk))
+		},
+		done: func(sse *sseWriter, identity convert.StreamIdentity, usage convert.Usage) error {
+			return writeEvents(sse, stream.Done(identity, usage))
+		},
+		reasoning: func(sse *sseWriter, identity convert.StreamIdentity, delta convert.ReasoningDelta) error {
+			return writeEvents(sse, stream.Reasoning(identity, delta))
+		},
+		fail: func(sse *sseWriter, err error) error {
+			status, _, _ := classifyBedrockError(err)
+			return sse.writeEvent("error", AnthropicErrorResponse{
+				Type:  "error",
+				Error: AnthropicError{Type: anthropicErrorType(status), Message: err.Error()},
+			})
+		},
+		conversionError: writeAnthropicConversionError,
+		bedrockError:    writeAnthropicBedrockError,
+	}
+}
@@ -0,0 +1,141 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/DefangLabs/bedrock-sidecar/convert"
+	"github.com/DefangLabs/bedrock-sidecar/handler"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+func TestHandleAnthropicMessages(t *testing.T) {
+	h := handler.Handler{
+		Converser: mockBedrockClient{
+			response: &bedrockruntime.ConverseOutput{
+				Output: &types.ConverseOutputMemberMessage{
+					Value: types.Message{
+						Role:    types.ConversationRoleAssistant,
+						Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "Hello"}},
+					},
+				},
+				StopReason: types.StopReasonMaxTokens,
+			},
+		},
+		ModelMap: bedrock.ModelMap{},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(
+		`{"model": "claude-sonnet", "max_tokens": 16, "messages": [{"role": "user", "content": "Hi"}]}`,
+	))
+	w := httptest.NewRecorder()
+	h.HandleAnthropicMessages(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp convert.AnthropicResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Type != "message" || len(resp.Content) != 1 || resp.Content[0].Text != "Hello" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if resp.StopReason == nil || *resp.StopReason != "max_tokens" {
+		t.Errorf("Expected stop_reason 'max_tokens', got %v", resp.StopReason)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(
+		`{"model": "claude-sonnet", "messages": [{"role": "user", "content": "Hi"}]}`,
+	))
+	w = httptest.NewRecorder()
+	h.HandleAnthropicMessages(w, req)
+	var errResp handler.AnthropicErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Failed to decode error: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || errResp.Error.Type != "invalid_request_error" {
+		t.Errorf("Expected invalid_request_error without max_tokens, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleStreamedAnthropicMessages(t *testing.T) {
+	h := handler.Handler{
+		Converser: mockBedrockClient{
+			events: []types.ConverseStreamOutput{
+				&types.ConverseStreamOutputMemberMessageStart{Value: types.MessageStartEvent{Role: types.ConversationRoleAssistant}},
+				&types.ConverseStreamOutputMemberContentBlockDelta{
+					Value: types.ContentBlockDeltaEvent{
+						ContentBlockIndex: aws.Int32(0),
+						Delta: &types.ContentBlockDeltaMemberReasoningContent{
+							Value: &types.ReasoningContentBlockDeltaMemberText{Value: "Hmm"},
+						},
+					},
+				},
+				&types.ConverseStreamOutputMemberContentBlockDelta{
+					Value: types.ContentBlockDeltaEvent{
+						ContentBlockIndex: aws.Int32(1),
+						Delta:             &types.ContentBlockDeltaMemberText{Value: "Hi"},
+					},
+				},
+				&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: types.StopReasonEndTurn}},
+			},
+		},
+		ModelMap: bedrock.ModelMap{},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(
+		`{"model": "claude-sonnet", "max_tokens": 2048, "stream": true, "thinking": {"type": "enabled", "budget_tokens": 1024}, "messages": [{"role": "user", "content": "Hello"}]}`,
+	))
+	w := httptest.NewRecorder()
+	h.HandleAnthropicMessages(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	var names []string
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			names = append(names, name)
+		}
+	}
+	expected := "message_start " +
+		"content_block_start content_block_delta content_block_stop " +
+		"content_block_start content_block_delta content_block_stop " +
+		"message_delta message_stop"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("Expected events %q, got %q", expected, strings.Join(names, " "))
+	}
+	if !strings.Contains(w.Body.String(), `"delta":{"type":"thinking_delta","thinking":"Hmm"}`) {
+		t.Errorf("Expected a thinking delta, got %q", w.Body.String())
+	}
+}
+
+func TestHandleStreamedAnthropicMessagesError(t *testing.T) {
+	h := handler.Handler{
+		Converser: mockBedrockClient{
+			err: &types.ThrottlingException{Message: aws.String("slow down")},
+		},
+		ModelMap: bedrock.ModelMap{},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(
+		`{"model": "claude-sonnet", "max_tokens": 16, "stream": true, "messages": [{"role": "user", "content": "Hello"}]}`,
+	))
+	w := httptest.NewRecorder()
+	h.HandleAnthropicMessages(w, req)
+
+	var errResp handler.AnthropicErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("Failed to decode error: %v", err)
+	}
+	if w.Code != http.StatusTooManyRequests || errResp.Error.Type != "rate_limit_error" {
+		t.Errorf("Expected rate_limit_error, got %d: %s", w.Code, w.Body.String())
+	}
+}
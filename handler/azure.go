@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/DefangLabs/bedrock-sidecar/convert"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/openai/openai-go"
+)
+
+// azureAPIVersion matches Azure OpenAI api-version values such as
+// 2024-10-21 and 2025-04-01-preview.
+var azureAPIVersion = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})(-preview)?$`)
+
+func validateAzureAPIVersion(version string) bool {
+	match := azureAPIVersion.FindStringSubmatch(version)
+	if match == nil {
+		return false
+	}
+	_, err := time.Parse(time.DateOnly, match[1])
+	return err == nil
+}
+
+// HandleAzureDeployments serves the Azure OpenAI routes,
+// /openai/deployments/{deployment}/{operation}?api-version=..., with the
+// deployment name used as the model. The api-key header is checked like
+// the Authorization header of the OpenAI routes.
+func (h Handler) HandleAzureDeployments(w http.ResponseWriter, r *http.Request) {
+	deployment, operation, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/openai/deployments/"), "/")
+	if deployment == "" {
+		writeError(w, http.StatusNotFound, errorTypeInvalidRequest, "404", "", "Resource not found")
+		return
+	}
+
+	version := r.URL.Query().Get("api-version")
+	if version == "" {
+		writeError(w, http.StatusBadRequest, errorTypeInvalidRequest, "missing_api_version", "api-version", "Missing required query parameter 'api-version'")
+		return
+	}
+	if !validateAzureAPIVersion(version) {
+		writeError(w, http.StatusBadRequest, errorTypeInvalidRequest, "invalid_api_version", "api-version", "Unsupported api-version '"+version+"'")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errorTypeInvalidRequest, "method_not_allowed", "", "Method not allowed")
+		return
+	}
+
+	switch operation {
+	case "chat/completions":
+		h.handleAzureChatCompletions(w, r, deployment)
+	case "completions":
+		h.handleAzureCompletions(w, r, deployment)
+	case "embeddings":
+		var openAIReq convert.OpenAIEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&openAIReq); err != nil {
+			writeInvalidRequest(w, "", "Invalid request body")
+			return
+		}
+		openAIReq.Model = deployment
+		slog.Debug("Received", "request", openAIReq)
+		h.serveEmbeddings(r.Context(), w, openAIReq)
+	default:
+		writeError(w, http.StatusNotFound, errorTypeInvalidRequest, "404", "", "Resource not found")
+	}
+}
+
+func (h Handler) handleAzureChatCompletions(w http.ResponseWriter, r *http.Request, deployment string) {
+	ctx := r.Context()
+	var openAIReq convert.OpenAIRequest
+	if err := json.NewDecoder(r.Body).Decode(&openAIReq); err != nil {
+		writeInvalidRequest(w, "", "Invalid request body")
+		return
+	}
+	openAIReq.Model = deployment
+	slog.Debug("Received", "request", openAIReq)
+
+	if err := h.validateChoices(openAIReq.N); err != nil {
+		writeInvalidRequest(w, "n", err.Error())
+		return
+	}
+
+	if openAIReq.Stream {
+		filter := convert.NewAzureStreamFilter()
+		encoder := dataChunkEncoder(
+			openAIReq,
+			func(chunk openai.ChatCompletionChunk) (interface{}, bool) {
+				return convert.ToAzureChatChunk(chunk), true
+			},
+			func(identity convert.StreamIdentity, usage convert.Usage) interface{} {
+				return convert.ToOpenAIUsageChunk(identity, usage, openAIReq.Model)
+			},
+		)
+		h.handleStreamedChatCompletion(ctx, w, openAIReq, withAzureFilter(encoder, filter, func(identity convert.StreamIdentity) interface{} {
+			return filter.FilterChunk(identity, openAIReq.Model)
+		}))
+		return
+	}
+
+	openAIResp, ok := h.azureConverse(ctx, w, openAIReq)
+	if !ok {
+		return
+	}
+	writeJSON(w, convert.ToAzureChatResponse(openAIResp))
+}
+
+func (h Handler) handleAzureCompletions(w http.ResponseWriter, r *http.Request, deployment string) {
+	ctx := r.Context()
+	var completionReq convert.OpenAICompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&completionReq); err != nil {
+		writeInvalidRequest(w, "", "Invalid request body")
+		return
+	}
+	completionReq.Model = deployment
+	slog.Debug("Received", "request", completionReq)
+
+	if err := h.validateChoices(completionReq.N); err != nil {
+		writeInvalidRequest(w, "n", err.Error())
+		return
+	}
+
+	openAIReq, err := completionReq.ToOpenAIRequest()
+	if err != nil {
+		writeInvalidRequest(w, "prompt", err.Error())
+		return
+	}
+
+	if openAIReq.Stream {
+		stream := convert.NewCompletionStream(completionReq)
+		filter := convert.NewAzureStreamFilter()
+		encoder := dataChunkEncoder(
+			openAIReq,
+			func(chunk openai.ChatCompletionChunk) (interface{}, bool) {
+				return stream.Chunk(chunk)
+			},
+			func(identity convert.StreamIdentity, usage convert.Usage) interface{} {
+				return stream.UsageChunk(identity, usage)
+			},
+		)
+		h.handleStreamedChatCompletion(ctx, w, openAIReq, withAzureFilter(encoder, filter, func(identity convert.StreamIdentity) interface{} {
+			return filter.CompletionFilterChunk(identity, openAIReq.Model)
+		}))
+		return
+	}
+
+	openAIResp, ok := h.azureConverse(ctx, w, openAIReq)
+	if !ok {
+		return
+	}
+	writeJSON(w, convert.ToAzureCompletionResponse(openAIResp, completionReq))
+}
+
+func (h Handler) azureConverse(ctx context.Context, w http.ResponseWriter, openAIReq convert.OpenAIRequest) (convert.OpenAIResponse, bool) {
+	bedrockReq, err := convert.ToBedrockRequest(ctx, h.ModelMap, openAIReq)
+	if err != nil {
+		writeConversionError(w, err)
+		return convert.OpenAIResponse{}, false
+	}
+	slog.Debug("Converted", "request", bedrockReq)
+
+	openAIResp, err := h.converse(ctx, bedrockReq, openAIReq)
+	if err != nil {
+		slog.Error("Failed to invoke Bedrock Converse", "error", err)
+		writeBedrockError(w, err)
+		return convert.OpenAIResponse{}, false
+	}
+	return openAIResp, true
+}
+
+// withAzureFilter collects the guardrail assessments of a stream and sends
+// them in one more chunk before the stream ends.
+func withAzureFilter(
+	encoder chunkEncoder,
+	filter *convert.AzureStreamFilter,
+	filterChunk func(convert.StreamIdentity) interface{},
+) chunkEncoder {
+	done := encoder.done
+	encoder.guardrail = func(choice int, assessment *types.GuardrailTraceAssessment) {
+		filter.Add(choice, assessment)
+	}
+	encoder.done = func(sse *sseWriter, identity convert.StreamIdentity, usage convert.Usage) error {
+		if err := sse.writeJSON(filterChunk(identity)); err != nil {
+			return err
+		}
+		return done(sse, identity, usage)
+	}
+	return encoder
+}
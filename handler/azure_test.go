@@ -0,0 +1,193 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/DefangLabs/bedrock-sidecar/convert"
+	"github.com/DefangLabs/bedrock-sidecar/handler"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// recordingBedrockClient records the model of each Converse call.
+type recordingBedrockClient struct {
+	mockBedrockClient
+	modelIDs *[]string
+}
+
+func (m recordingBedrockClient) Converse(
+	ctx context.Context,
+	params *bedrockruntime.ConverseInput,
+	optFns ...func(*bedrockruntime.Options),
+) (*bedrockruntime.ConverseOutput, error) {
+	*m.modelIDs = append(*m.modelIDs, aws.ToString(params.ModelId))
+	return m.mockBedrockClient.Converse(ctx, params, optFns...)
+}
+
+func TestHandleAzureDeployments(t *testing.T) {
+	var modelIDs []string
+	h := handler.Handler{
+		Converser: recordingBedrockClient{
+			mockBedrockClient: mockBedrockClient{
+				response: &bedrockruntime.ConverseOutput{
+					Output: &types.ConverseOutputMemberMessage{
+						Value: types.Message{
+							Role:    types.ConversationRoleAssistant,
+							Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "Hello"}},
+						},
+					},
+					StopReason: types.StopReasonEndTurn,
+				},
+			},
+			modelIDs: &modelIDs,
+		},
+		ModelMap: bedrock.ModelMap{Aliases: map[string]bedrock.ModelConfig{"my-deployment": {ModelID: "anthropic.claude"}}},
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "chat completions",
+			path:         "/openai/deployments/my-deployment/chat/completions?api-version=2024-10-21",
+			body:         `{"messages": [{"role": "user", "content": "Hi"}]}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "completions with a preview version",
+			path:         "/openai/deployments/my-deployment/completions?api-version=2025-04-01-preview",
+			body:         `{"prompt": "Hi"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "missing api-version",
+			path:         "/openai/deployments/my-deployment/chat/completions",
+			body:         `{"messages": [{"role": "user", "content": "Hi"}]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid api-version",
+			path:         "/openai/deployments/my-deployment/chat/completions?api-version=2024-13-01",
+			body:         `{"messages": [{"role": "user", "content": "Hi"}]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown operation",
+			path:         "/openai/deployments/my-deployment/images/generations?api-version=2024-10-21",
+			body:         `{}`,
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("api-key", "ignored")
+			w := httptest.NewRecorder()
+			h.HandleAzureDeployments(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedCode, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp map[string]json.RawMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if _, ok := resp["prompt_filter_results"]; !ok {
+				t.Errorf("Expected prompt_filter_results, got %s", w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), `"content_filter_results"`) {
+				t.Errorf("Expected content_filter_results, got %s", w.Body.String())
+			}
+			if model := string(resp["model"]); model != `"my-deployment"` {
+				t.Errorf("Expected model my-deployment, got %s", model)
+			}
+		})
+	}
+
+	for _, modelID := range modelIDs {
+		if modelID != "anthropic.claude" {
+			t.Errorf("Expected deployment to resolve to anthropic.claude, got %q", modelID)
+		}
+	}
+	if len(modelIDs) != 2 {
+		t.Errorf("Expected 2 Bedrock calls, got %d", len(modelIDs))
+	}
+}
+
+func TestHandleAzureStreamedChatCompletions(t *testing.T) {
+	h := handler.Handler{
+		Converser: mockBedrockClient{
+			events: []types.ConverseStreamOutput{
+				&types.ConverseStreamOutputMemberMessageStart{Value: types.MessageStartEvent{Role: types.ConversationRoleAssistant}},
+				&types.ConverseStreamOutputMemberContentBlockDelta{
+					Value: types.ContentBlockDeltaEvent{
+						ContentBlockIndex: aws.Int32(0),
+						Delta:             &types.ContentBlockDeltaMemberText{Value: "Hi"},
+					},
+				},
+				&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: types.StopReasonEndTurn}},
+				&types.ConverseStreamOutputMemberMetadata{
+					Value: types.ConverseStreamMetadataEvent{
+						Usage: &types.TokenUsage{InputTokens: aws.Int32(2), OutputTokens: aws.Int32(1)},
+						Trace: &types.ConverseStreamTrace{Guardrail: &types.GuardrailTraceAssessment{
+							OutputAssessments: map[string][]types.GuardrailAssessment{"gr-1": {{}}},
+						}},
+					},
+				},
+			},
+		},
+		ModelMap: bedrock.ModelMap{},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/openai/deployments/gpt-4o/chat/completions?api-version=2024-10-21", strings.NewReader(
+		`{"messages": [{"role": "user", "content": "Hello"}], "stream": true}`,
+	))
+	w := httptest.NewRecorder()
+	h.HandleAzureDeployments(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	type chunk struct {
+		Choices []struct {
+			ContentFilterResults *convert.ContentFilterResults `json:"content_filter_results"`
+		} `json:"choices"`
+		PromptFilterResults []convert.PromptFilterResult `json:"prompt_filter_results"`
+	}
+	var chunks []chunk
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var c chunk
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			t.Fatalf("Failed to decode chunk %q: %v", data, err)
+		}
+		chunks = append(chunks, c)
+	}
+
+	last := chunks[len(chunks)-1]
+	if len(last.PromptFilterResults) != 1 || len(last.Choices) != 1 {
+		t.Fatalf("Expected a final filter chunk, got %+v", last)
+	}
+	if results := last.Choices[0].ContentFilterResults; results == nil || results.Violence == nil || results.Violence.Severity != "safe" {
+		t.Errorf("Expected content filter results from the guardrail trace, got %+v", results)
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/DefangLabs/bedrock-sidecar/convert"
+	"github.com/openai/openai-go"
+)
+
+// HandleCompletions serves the legacy text completions API by running the
+// prompt as a single-turn chat completion.
+func (h Handler) HandleCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errorTypeInvalidRequest, "method_not_allowed", "", "Method not allowed")
+		return
+	}
+
+	ctx := r.Context()
+	var completionReq convert.OpenAICompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&completionReq); err != nil {
+		writeInvalidRequest(w, "", "Invalid request body")
+		return
+	}
+	slog.Debug("Received", "request", completionReq)
+
+	if err := h.validateChoices(completionReq.N); err != nil {
+		writeInvalidRequest(w, "n", err.Error())
+		return
+	}
+
+	openAIReq, err := completionReq.ToOpenAIRequest()
+	if err != nil {
+		writeInvalidRequest(w, "prompt", err.Error())
+		return
+	}
+
+	if openAIReq.Stream {
+		stream := convert.NewCompletionStream(completionReq)
+		h.handleStreamedChatCompletion(ctx, w, openAIReq, dataChunkEncoder(
+			openAIReq,
+			func(chunk openai.ChatCompletionChunk) (interface{}, bool) {
+				return stream.Chunk(chunk)
+			},
+			func(identity convert.StreamIdentity, usage convert.Usage) interface{} {
+				return stream.UsageChunk(identity, usage)
+			},
+		))
+		return
+	}
+
+	bedrockReq, err := convert.ToBedrockRequest(r.Context(), h.ModelMap, openAIReq)
+	if err != nil {
+		writeConversionError(w, err)
+		return
+	}
+	slog.Debug("Converted", "request", bedrockReq)
+
+	openAIResp, err := h.converse(ctx, bedrockReq, openAIReq)
+	if err != nil {
+		slog.Error("Failed to invoke Bedrock Converse", "error", err)
+		writeBedrockError(w, err)
+		return
+	}
+
+	writeJSON(w, convert.ToOpenAICompletionResponse(openAIResp, completionReq))
+}
@@ -0,0 +1,131 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/DefangLabs/bedrock-sidecar/convert"
+	"github.com/DefangLabs/bedrock-sidecar/handler"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+func TestHandleCompletions(t *testing.T) {
+	h := handler.Handler{
+		Converser: mockBedrockClient{
+			response: &bedrockruntime.ConverseOutput{
+				Output: &types.ConverseOutputMemberMessage{
+					Value: types.Message{
+						Role:    types.ConversationRoleAssistant,
+						Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: " world"}},
+					},
+				},
+				StopReason: types.StopReasonEndTurn,
+			},
+		},
+		ModelMap: bedrock.ModelMap{},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(
+		`{"model": "gpt-3.5-turbo-instruct", "prompt": "Hello", "echo": true, "n": 2}`,
+	))
+	w := httptest.NewRecorder()
+	h.HandleCompletions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var resp convert.OpenAICompletionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Object != "text_completion" {
+		t.Errorf("Expected object 'text_completion', got %q", resp.Object)
+	}
+	if len(resp.Choices) != 2 {
+		t.Fatalf("Expected 2 choices, got %d", len(resp.Choices))
+	}
+	for i, choice := range resp.Choices {
+		if choice.Index != i || choice.Text != "Hello world" {
+			t.Errorf("Unexpected choice %d: %+v", i, choice)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(
+		`{"model": "gpt-3.5-turbo-instruct", "prompt": ["a", "b"]}`,
+	))
+	w = httptest.NewRecorder()
+	h.HandleCompletions(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for multiple prompts, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestHandleStreamedCompletions(t *testing.T) {
+	h := handler.Handler{
+		Converser: mockBedrockClient{
+			events: []types.ConverseStreamOutput{
+				&types.ConverseStreamOutputMemberMessageStart{Value: types.MessageStartEvent{Role: types.ConversationRoleAssistant}},
+				&types.ConverseStreamOutputMemberContentBlockDelta{
+					Value: types.ContentBlockDeltaEvent{
+						ContentBlockIndex: aws.Int32(0),
+						Delta:             &types.ContentBlockDeltaMemberText{Value: "Hi"},
+					},
+				},
+				&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: types.StopReasonEndTurn}},
+				&types.ConverseStreamOutputMemberMetadata{
+					Value: types.ConverseStreamMetadataEvent{
+						Usage: &types.TokenUsage{InputTokens: aws.Int32(2), OutputTokens: aws.Int32(1)},
+					},
+				},
+			},
+		},
+		ModelMap: bedrock.ModelMap{},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(
+		`{"model": "gpt-3.5-turbo-instruct", "prompt": "Hello", "stream": true, "stream_options": {"include_usage": true}}`,
+	))
+	w := httptest.NewRecorder()
+	h.HandleCompletions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.HasSuffix(w.Body.String(), "data: [DONE]\n\n") {
+		t.Errorf("Expected stream to end with [DONE], got %q", w.Body.String())
+	}
+
+	var chunks []convert.OpenAICompletionResponse
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk convert.OpenAICompletionResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("Failed to decode chunk %q: %v", data, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	// A text chunk, a finish chunk and a usage chunk.
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d: %q", len(chunks), w.Body.String())
+	}
+	if chunks[0].Object != "text_completion" || chunks[0].Choices[0].Text != "Hi" {
+		t.Errorf("Unexpected first chunk: %+v", chunks[0])
+	}
+	if reason := chunks[1].Choices[0].FinishReason; reason == nil || *reason != "stop" {
+		t.Errorf("Expected finish_reason 'stop', got %v", reason)
+	}
+	if chunks[2].Usage == nil || chunks[2].Usage.TotalTokens != 3 {
+		t.Errorf("Expected usage of 3 tokens, got %+v", chunks[2].Usage)
+	}
+}
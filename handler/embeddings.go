@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/DefangLabs/bedrock-sidecar/convert"
+)
+
+func (h Handler) HandleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errorTypeInvalidRequest, "method_not_allowed", "", "Method not allowed")
+		return
+	}
+
+	var openAIReq convert.OpenAIEmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&openAIReq); err != nil {
+		writeInvalidRequest(w, "", "Invalid request body")
+		return
+	}
+	slog.Debug("Received", "request", openAIReq)
+
+	h.serveEmbeddings(r.Context(), w, openAIReq)
+}
+
+// serveEmbeddings embeds the inputs of openAIReq, batching them as the
+// model allows.
+func (h Handler) serveEmbeddings(ctx context.Context, w http.ResponseWriter, openAIReq convert.OpenAIEmbeddingRequest) {
+	bedrockReqs, err := convert.ToBedrockEmbeddingRequests(h.ModelMap, openAIReq)
+	if err != nil {
+		writeConversionError(w, err)
+		return
+	}
+
+	outputs := make([]convert.EmbeddingOutput, len(bedrockReqs))
+	errs := make([]error, len(bedrockReqs))
+	limit := h.concurrencyLimit()
+	var wg sync.WaitGroup
+	for i := range bedrockReqs {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			output, err := h.Embedder.InvokeModel(ctx, &bedrockReqs[index])
+			if err != nil {
+				errs[index] = err
+				return
+			}
+			outputs[index] = convert.EmbeddingOutput{
+				Body:        output.Body,
+				InputTokens: bedrock.InputTokenCount(output),
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			slog.Error("Failed to invoke Bedrock InvokeModel", "error", err)
+			writeBedrockError(w, err)
+			return
+		}
+	}
+
+	openAIResp, err := convert.ToOpenAIEmbeddingResponse(h.ModelMap, openAIReq, outputs)
+	if err != nil {
+		slog.Error("Failed to convert embeddings", "error", err)
+		writeError(w, http.StatusInternalServerError, errorTypeServer, "", "", err.Error())
+		return
+	}
+	writeJSON(w, openAIResp)
+}
@@ -0,0 +1,123 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/DefangLabs/bedrock-sidecar/convert"
+	"github.com/DefangLabs/bedrock-sidecar/handler"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// mockEmbedder answers Titan requests with an embedding of the input length.
+type mockEmbedder struct {
+	err   error
+	mu    sync.Mutex
+	calls int
+}
+
+func (m *mockEmbedder) InvokeModel(
+	_ context.Context,
+	params *bedrockruntime.InvokeModelInput,
+	_ ...func(*bedrockruntime.Options),
+) (*bedrockruntime.InvokeModelOutput, error) {
+	m.mu.Lock()
+	m.calls++
+	m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	var body struct {
+		InputText string `json:"inputText"`
+	}
+	if err := json.Unmarshal(params.Body, &body); err != nil {
+		return nil, err
+	}
+	resp, err := json.Marshal(map[string]interface{}{
+		"embedding":           []float64{float64(len(body.InputText))},
+		"inputTextTokenCount": 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &bedrockruntime.InvokeModelOutput{Body: resp}, nil
+}
+
+func TestHandleEmbeddings(t *testing.T) {
+	modelMap := bedrock.ModelMap{Aliases: map[string]bedrock.ModelConfig{"text-embedding-3-small": {ModelID: "amazon.titan-embed-text-v2:0"}}}
+
+	tests := []struct {
+		name           string
+		body           string
+		embedder       *mockEmbedder
+		expectedStatus int
+		expectedCalls  int
+	}{
+		{
+			name:           "Batch input",
+			body:           `{"model": "text-embedding-3-small", "input": ["a", "bb", "ccc"]}`,
+			embedder:       &mockEmbedder{},
+			expectedStatus: http.StatusOK,
+			expectedCalls:  3,
+		},
+		{
+			name:           "Token arrays",
+			body:           `{"model": "text-embedding-3-small", "input": [[1, 2]]}`,
+			embedder:       &mockEmbedder{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Bedrock error",
+			body:           `{"model": "text-embedding-3-small", "input": "a"}`,
+			embedder:       &mockEmbedder{err: &types.ThrottlingException{Message: aws.String("slow down")}},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedCalls:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h := handler.Handler{Embedder: tt.embedder, ModelMap: modelMap}
+			h.HandleEmbeddings(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.embedder.calls != tt.expectedCalls {
+				t.Errorf("Expected %d InvokeModel calls, got %d", tt.expectedCalls, tt.embedder.calls)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp convert.OpenAIEmbeddingResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Model != "text-embedding-3-small" {
+				t.Errorf("Expected model 'text-embedding-3-small', got %q", resp.Model)
+			}
+			for i, data := range resp.Data {
+				embedding, ok := data.Embedding.([]interface{})
+				if data.Index != i || !ok || embedding[0] != float64(i+1) {
+					t.Errorf("Expected embedding %d to be in input order, got %+v", i, data)
+				}
+			}
+			if resp.Usage.PromptTokens != 3 || resp.Usage.TotalTokens != 3 {
+				t.Errorf("Expected 3 tokens, got %+v", resp.Usage)
+			}
+		})
+	}
+}
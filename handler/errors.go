@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+type OpenAIErrorResponse struct {
+	Error OpenAIError `json:"error"`
+}
+
+type OpenAIError struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Code    *string `json:"code"`
+	Param   *string `json:"param"`
+}
+
+const (
+	errorTypeInvalidRequest = "invalid_request_error"
+	errorTypePermission     = "permission_error"
+	errorTypeRateLimit      = "rate_limit_error"
+	errorTypeServer         = "server_error"
+)
+
+func writeError(w http.ResponseWriter, status int, errType, code, param, message string) {
+	body := OpenAIErrorResponse{
+		Error: OpenAIError{
+			Message: message,
+			Type:    errType,
+		},
+	}
+	if code != "" {
+		body.Error.Code = &code
+	}
+	if param != "" {
+		body.Error.Param = &param
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Error("Failed to encode error response", "error", err)
+	}
+}
+
+func writeInvalidRequest(w http.ResponseWriter, param, message string) {
+	writeError(w, http.StatusBadRequest, errorTypeInvalidRequest, "", param, message)
+}
+
+// conversionErrorStatus returns the HTTP status for a request that could
+// not be converted for Bedrock: models a strict model map rejects are not
+// found, anything else is invalid.
+func conversionErrorStatus(err error) int {
+	if errors.Is(err, bedrock.ErrModelNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+func writeConversionError(w http.ResponseWriter, err error) {
+	if conversionErrorStatus(err) == http.StatusNotFound {
+		writeError(w, http.StatusNotFound, errorTypeInvalidRequest, "model_not_found", "model", err.Error())
+		return
+	}
+	writeInvalidRequest(w, "", err.Error())
+}
+
+// classifyBedrockError maps a Bedrock error onto the HTTP status, OpenAI
+// error type and code that OpenAI SDKs use to decide whether to retry.
+func classifyBedrockError(err error) (int, string, string) {
+	var validation *types.ValidationException
+	var accessDenied *types.AccessDeniedException
+	var notFound *types.ResourceNotFoundException
+	var throttling *types.ThrottlingException
+	var quotaExceeded *types.ServiceQuotaExceededException
+	var modelNotReady *types.ModelNotReadyException
+	var unavailable *types.ServiceUnavailableException
+
+	switch {
+	case errors.As(err, &validation):
+		return http.StatusBadRequest, errorTypeInvalidRequest, "invalid_request"
+	case errors.As(err, &accessDenied):
+		return http.StatusForbidden, errorTypePermission, "access_denied"
+	case errors.As(err, &notFound):
+		return http.StatusNotFound, errorTypeInvalidRequest, "model_not_found"
+	case errors.As(err, &throttling):
+		return http.StatusTooManyRequests, errorTypeRateLimit, "rate_limit_exceeded"
+	case errors.As(err, &quotaExceeded):
+		return http.StatusTooManyRequests, errorTypeRateLimit, "quota_exceeded"
+	case errors.As(err, &modelNotReady):
+		return http.StatusServiceUnavailable, errorTypeServer, "model_not_ready"
+	case errors.As(err, &unavailable):
+		return http.StatusServiceUnavailable, errorTypeServer, "service_unavailable"
+	default:
+		return http.StatusInternalServerError, errorTypeServer, ""
+	}
+}
+
+func writeBedrockError(w http.ResponseWriter, err error) {
+	status, errType, code := classifyBedrockError(err)
+	writeError(w, status, errType, code, "", err.Error())
+}
@@ -3,101 +3,489 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/DefangLabs/bedrock-sidecar/bedrock"
 	"github.com/DefangLabs/bedrock-sidecar/convert"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/openai/openai-go"
+)
+
+const (
+	DefaultMaxChoices        = 8
+	DefaultMaxConcurrency    = 4
+	DefaultMaxStreamDuration = 15 * time.Minute
+	DefaultStreamIdleTimeout = 2 * time.Minute
+	DefaultHeartbeatInterval = 15 * time.Second
+
+	// streamDeadlineGrace leaves time to report a stream timeout to the
+	// client before the connection's write deadline passes.
+	streamDeadlineGrace = 10 * time.Second
+)
+
+var (
+	errMaxStreamDuration = errors.New("stream exceeded the maximum duration")
+	errStreamIdle        = errors.New("no data received from Bedrock within the idle timeout")
 )
 
 type Handler struct {
-	Converser bedrock.BedrockConverser
+	Converser bedrock.Converser
+	Embedder  bedrock.Embedder
 	ModelMap  bedrock.ModelMap
+	// MaxChoices is the largest accepted n.
+	MaxChoices int
+	// MaxConcurrency bounds the Bedrock calls made at once for a single request.
+	MaxConcurrency int
+	// MaxStreamDuration bounds a streamed response, which is exempt from the
+	// server's write timeout.
+	MaxStreamDuration time.Duration
+	// StreamIdleTimeout ends a stream when Bedrock sends nothing for this long.
+	StreamIdleTimeout time.Duration
+	// HeartbeatInterval is how often a comment is sent on a quiet stream.
+	HeartbeatInterval time.Duration
+	// Catalog, if set, adds the account's Bedrock models to /v1/models.
+	Catalog *bedrock.Catalog
+	// Responses stores responses for the Responses API. Without it, responses
+	// cannot be retrieved or continued.
+	Responses *ResponseStore
+	// APIKeys, if set, are the keys clients must authenticate with.
+	APIKeys []string
 }
 
 func (h Handler) HandleChatCompletions(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, errorTypeInvalidRequest, "method_not_allowed", "", "Method not allowed")
 		return
 	}
 
 	ctx := r.Context()
 	var openAIReq convert.OpenAIRequest
 	if err := json.NewDecoder(r.Body).Decode(&openAIReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeInvalidRequest(w, "", "Invalid request body")
 		return
 	}
 
 	slog.Debug("Received", "request", openAIReq)
 
+	if err := h.validateChoices(openAIReq.N); err != nil {
+		writeInvalidRequest(w, "n", err.Error())
+		return
+	}
+
 	if openAIReq.Stream {
-		h.handleStreamedChatCompletion(ctx, w, openAIReq)
+		h.handleStreamedChatCompletion(ctx, w, openAIReq, chatChunkEncoder(openAIReq))
 	} else {
 		h.handleBufferedChatCompletion(ctx, w, openAIReq)
 	}
 }
 
+func (h Handler) validateChoices(n int) error {
+	maxChoices := h.MaxChoices
+	if maxChoices <= 0 {
+		maxChoices = DefaultMaxChoices
+	}
+	if n < 0 || n > maxChoices {
+		return fmt.Errorf("n must be between 1 and %d", maxChoices)
+	}
+	return nil
+}
+
+func choiceCount(openAIReq convert.OpenAIRequest) int {
+	if openAIReq.N < 1 {
+		return 1
+	}
+	return openAIReq.N
+}
+
+func (h Handler) concurrencyLimit() chan struct{} {
+	limit := h.MaxConcurrency
+	if limit <= 0 {
+		limit = DefaultMaxConcurrency
+	}
+	return make(chan struct{}, limit)
+}
+
+func orDefault(value, fallback time.Duration) time.Duration {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
+
+type streamEvent struct {
+	chunk openai.ChatCompletionChunk
+	// alive stands for a Bedrock event without a chunk, such as metadata,
+	// which still shows that the stream is making progress.
+	alive bool
+	// reasoning is a piece of the model's thinking, which has no chunk.
+	reasoning *convert.ReasoningDelta
+	// usage ends a choice, together with its guardrail assessment.
+	usage     *convert.Usage
+	choice    int
+	guardrail *types.GuardrailTraceAssessment
+	err       error
+}
+
+// chunkEncoder writes a stream in the format of the API being served.
+type chunkEncoder struct {
+	chunk func(*sseWriter, openai.ChatCompletionChunk) error
+	// done ends a stream that completed, given the usage of all its choices.
+	done func(*sseWriter, convert.StreamIdentity, convert.Usage) error
+	// fail reports an error once the stream has started.
+	fail func(*sseWriter, error) error
+	// guardrail, if set, receives each choice's guardrail assessment.
+	guardrail func(int, *types.GuardrailTraceAssessment)
+	// reasoning, if set, writes the model's thinking, which is otherwise
+	// left out.
+	reasoning func(*sseWriter, convert.StreamIdentity, convert.ReasoningDelta) error
+	// conversionError and bedrockError write errors that happen before the
+	// stream starts. They default to OpenAI errors.
+	conversionError func(http.ResponseWriter, error)
+	bedrockError    func(http.ResponseWriter, error)
+	// ndjson streams newline-delimited JSON instead of server-sent events.
+	// Such streams have no comments, so they get no heartbeats either.
+	ndjson bool
+}
+
+func (e chunkEncoder) writeConversionError(w http.ResponseWriter, err error) {
+	if e.conversionError != nil {
+		e.conversionError(w, err)
+		return
+	}
+	writeConversionError(w, err)
+}
+
+func (e chunkEncoder) writeBedrockError(w http.ResponseWriter, err error) {
+	if e.bedrockError != nil {
+		e.bedrockError(w, err)
+		return
+	}
+	writeBedrockError(w, err)
+}
+
+func chatChunkEncoder(openAIReq convert.OpenAIRequest) chunkEncoder {
+	return dataChunkEncoder(
+		openAIReq,
+		func(chunk openai.ChatCompletionChunk) (interface{}, bool) {
+			return chunk, true
+		},
+		func(identity convert.StreamIdentity, usage convert.Usage) interface{} {
+			return convert.ToOpenAIUsageChunk(identity, usage, openAIReq.Model)
+		},
+	)
+}
+
+// dataChunkEncoder writes chunks as data-only events ending in [DONE], with
+// a usage chunk first if the client asked for one. encode reports false for
+// chunks that should not be sent.
+func dataChunkEncoder(
+	openAIReq convert.OpenAIRequest,
+	encode func(openai.ChatCompletionChunk) (interface{}, bool),
+	usageChunk func(convert.StreamIdentity, convert.Usage) interface{},
+) chunkEncoder {
+	return chunkEncoder{
+		chunk: func(sse *sseWriter, chunk openai.ChatCompletionChunk) error {
+			converted, ok := encode(chunk)
+			if !ok {
+				return nil
+			}
+			slog.Debug("Converted", "chunk", converted)
+			return sse.writeJSON(converted)
+		},
+		done: func(sse *sseWriter, identity convert.StreamIdentity, usage convert.Usage) error {
+			if openAIReq.StreamOptions != nil && openAIReq.StreamOptions.IncludeUsage {
+				if err := sse.writeJSON(usageChunk(identity, usage)); err != nil {
+					return err
+				}
+			}
+			return sse.writeDone()
+		},
+		fail: func(sse *sseWriter, err error) error {
+			return sse.writeError(err)
+		},
+	}
+}
+
 func (h Handler) handleStreamedChatCompletion(
 	ctx context.Context,
 	w http.ResponseWriter,
 	openAIReq convert.OpenAIRequest,
+	encoder chunkEncoder,
 ) {
-	bedrockReq := convert.ToBedrockStreamRequest(h.ModelMap, openAIReq)
-	slog.Debug("Converted", "request", bedrockReq)
-	bedrockResp, err := h.Converser.ConverseStream(ctx, &bedrockReq)
+	bedrockReq, err := convert.ToBedrockStreamRequest(ctx, h.ModelMap, openAIReq)
 	if err != nil {
-		slog.Error("Failed to invoke Bedrock ConverseStream", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		encoder.writeConversionError(w, err)
 		return
 	}
+	slog.Debug("Converted", "request", bedrockReq)
 
-	slog.Debug("Received", "response", bedrockResp)
-	flusher, ok := w.(http.Flusher)
+	sse, ok := newSSEWriter(w)
 	if !ok {
-		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, errorTypeServer, "", "", "Streaming not supported")
 		return
 	}
+	sse.ndjson = encoder.ndjson
 
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	for event := range bedrockResp.GetStream().Events() {
-		slog.Debug("Received", "chunk", event)
-		openAIChunk := convert.ToOpenAIResponseChunk(event, openAIReq.Model)
-		slog.Debug("Converted", "chunk", openAIChunk)
-		data, err := json.Marshal(openAIChunk)
-		if err != nil {
-			slog.Error("Failed to encode response", "error", err)
-			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	maxDuration := orDefault(h.MaxStreamDuration, DefaultMaxStreamDuration)
+	idleTimeout := orDefault(h.StreamIdleTimeout, DefaultStreamIdleTimeout)
+	if err := sse.setDeadline(time.Now().Add(maxDuration + streamDeadlineGrace)); err != nil {
+		slog.Warn("Failed to extend stream deadline", "error", err)
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, maxDuration, errMaxStreamDuration)
+	defer cancel()
+
+	identity := convert.NewStreamIdentity()
+	events := make(chan streamEvent)
+	limit := h.concurrencyLimit()
+	var wg sync.WaitGroup
+	for i := 0; i < choiceCount(openAIReq); i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			select {
+			case limit <- struct{}{}:
+				defer func() { <-limit }()
+			case <-ctx.Done():
+				return
+			}
+			h.streamChoice(ctx, index, bedrockReq, convert.NewChunkConverterWithIdentity(identity, openAIReq.Model), events)
+		}(i)
+	}
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+
+	fail := func(err error) {
+		slog.Error("Failed to invoke Bedrock ConverseStream", "error", err)
+		if !sse.started {
+			encoder.writeBedrockError(w, err)
 			return
 		}
-		message := []byte(fmt.Sprintf("data: %s\n\n", data))
-		if _, err := w.Write(message); err != nil {
-			slog.Error("Failed to write data", "error", err)
-			http.Error(w, "Failed to write data", http.StatusInternalServerError)
+		if err := encoder.fail(sse, err); err != nil {
+			slog.Error("Failed to write error event", "error", err)
+		}
+	}
+
+	var heartbeats <-chan time.Time
+	if !encoder.ndjson {
+		heartbeat := time.NewTicker(orDefault(h.HeartbeatInterval, DefaultHeartbeatInterval))
+		defer heartbeat.Stop()
+		heartbeats = heartbeat.C
+	}
+	idle := time.NewTimer(idleTimeout)
+	defer idle.Stop()
+
+	var usage convert.Usage
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			if cause := context.Cause(ctx); errors.Is(cause, errMaxStreamDuration) {
+				fail(cause)
+			}
 			return
+		case <-idle.C:
+			fail(errStreamIdle)
+			return
+		case <-heartbeats:
+			if err := sse.writeComment("keep-alive"); err != nil {
+				slog.Error("Failed to write heartbeat", "error", err)
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				done = true
+				break
+			}
+			resetTimer(idle, idleTimeout)
+			if event.alive {
+				continue
+			}
+			if event.reasoning != nil {
+				if encoder.reasoning == nil {
+					continue
+				}
+				if err := encoder.reasoning(sse, identity, *event.reasoning); err != nil {
+					slog.Error("Failed to write reasoning", "error", err)
+					return
+				}
+				continue
+			}
+
+			if event.usage != nil {
+				usage = usage.Add(*event.usage)
+				if encoder.guardrail != nil {
+					encoder.guardrail(event.choice, event.guardrail)
+				}
+				continue
+			}
+			if event.err != nil {
+				fail(event.err)
+				return
+			}
+
+			if err := encoder.chunk(sse, event.chunk); err != nil {
+				slog.Error("Failed to write chunk", "error", err)
+				return
+			}
 		}
-		flusher.Flush()
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err := encoder.done(sse, identity, usage); err != nil {
+		slog.Error("Failed to write end of stream", "error", err)
 	}
 }
 
+// streamChoice runs one ConverseStream call and forwards its chunks, tagged
+// with the choice index, to events.
+func (h Handler) streamChoice(
+	ctx context.Context,
+	index int,
+	bedrockReq bedrockruntime.ConverseStreamInput,
+	converter *convert.ChunkConverter,
+	events chan<- streamEvent,
+) {
+	send := func(event streamEvent) bool {
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	stream, err := h.Converser.ConverseStream(ctx, &bedrockReq)
+	if err != nil {
+		send(streamEvent{err: err})
+		return
+	}
+	defer stream.Close()
+
+	// Ranging over the events would block until Bedrock sends the next one,
+	// so watch the context as well and stop paying for tokens nobody reads.
+	chunks, streamed := 0, 0
+	streamEvents := stream.Events()
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			logAbandonedStream(ctx, index, chunks, streamed)
+			return
+		case event, ok := <-streamEvents:
+			if !ok {
+				done = true
+				break
+			}
+			slog.Debug("Received", "chunk", event, "choice", index)
+			chunk, ok := converter.ToOpenAIResponseChunk(event)
+			if !ok {
+				next := streamEvent{alive: true}
+				if reasoning, ok := converter.ReasoningDelta(event); ok {
+					next = streamEvent{reasoning: &reasoning}
+				}
+				if !send(next) {
+					logAbandonedStream(ctx, index, chunks, streamed)
+					return
+				}
+				if next.reasoning != nil {
+					streamed += len(next.reasoning.Text)
+				}
+				continue
+			}
+			for i := range chunk.Choices {
+				chunk.Choices[i].Index = int64(index)
+			}
+			if !send(streamEvent{chunk: chunk}) {
+				logAbandonedStream(ctx, index, chunks, streamed)
+				return
+			}
+			chunks++
+			streamed += streamedLength(chunk)
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		send(streamEvent{err: err})
+		return
+	}
+
+	usage := converter.Usage()
+	send(streamEvent{usage: &usage, choice: index, guardrail: converter.Guardrail()})
+}
+
+// logAbandonedStream records a stream that was closed before Bedrock
+// finished it. Bedrock only reports usage at the end of a stream, so the
+// completion tokens are estimated from the streamed length.
+func logAbandonedStream(ctx context.Context, index int, chunks int, streamed int) {
+	tokens := estimateTokens(streamed)
+	abandonedStreams.Add(1)
+	abandonedStreamTokens.Add(int64(tokens))
+	slog.Warn("Abandoned Bedrock stream",
+		"reason", context.Cause(ctx),
+		"choice", index,
+		"chunks", chunks,
+		"estimated_completion_tokens", tokens,
+	)
+}
+
+// streamedLength returns the length of the text and tool arguments a chunk
+// carries.
+func streamedLength(chunk openai.ChatCompletionChunk) int {
+	length := 0
+	for _, choice := range chunk.Choices {
+		length += len(choice.Delta.Content)
+		for _, call := range choice.Delta.ToolCalls {
+			length += len(call.Function.Arguments)
+		}
+	}
+	return length
+}
+
+// estimateTokens assumes the usual four bytes per token.
+func estimateTokens(length int) int {
+	return (length + 3) / 4
+}
+
 func (h Handler) handleBufferedChatCompletion(
 	ctx context.Context,
 	w http.ResponseWriter,
 	openAIReq convert.OpenAIRequest,
 ) {
-	bedrockReq := convert.ToBedrockRequest(h.ModelMap, openAIReq)
+	bedrockReq, err := convert.ToBedrockRequest(ctx, h.ModelMap, openAIReq)
+	if err != nil {
+		writeConversionError(w, err)
+		return
+	}
 	slog.Debug("Converted", "request", bedrockReq)
-	bedrockResp, err := h.Converser.Converse(ctx, &bedrockReq)
+
+	openAIResp, err := h.converse(ctx, bedrockReq, openAIReq)
 	if err != nil {
 		slog.Error("Failed to invoke Bedrock Converse", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeBedrockError(w, err)
 		return
 	}
-	slog.Debug("Received", "response", bedrockResp)
-	openAIResp := convert.ToOpenAIResponse(bedrockResp, openAIReq.Model)
-	slog.Debug("Converted", "response", bedrockResp)
+
+	slog.Debug("Converted", "response", openAIResp)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(openAIResp); err != nil {
 		slog.Error("Failed to encode response", "error", err)
@@ -105,3 +493,42 @@ func (h Handler) handleBufferedChatCompletion(
 		return
 	}
 }
+
+// converse makes one Converse call per requested choice and merges the
+// responses.
+func (h Handler) converse(
+	ctx context.Context,
+	bedrockReq bedrockruntime.ConverseInput,
+	openAIReq convert.OpenAIRequest,
+) (convert.OpenAIResponse, error) {
+	n := choiceCount(openAIReq)
+	responses := make([]convert.OpenAIResponse, n)
+	errs := make([]error, n)
+	limit := h.concurrencyLimit()
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			bedrockReq := bedrockReq
+			bedrockResp, err := h.Converser.Converse(ctx, &bedrockReq)
+			if err != nil {
+				errs[index] = err
+				return
+			}
+			slog.Debug("Received", "response", bedrockResp, "choice", index)
+			responses[index] = convert.ToOpenAIResponse(bedrockResp, openAIReq.Model)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return convert.OpenAIResponse{}, err
+		}
+	}
+	return convert.MergeOpenAIResponses(responses), nil
+}
@@ -4,20 +4,31 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
 	"github.com/DefangLabs/bedrock-sidecar/convert"
 	"github.com/DefangLabs/bedrock-sidecar/handler"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/openai/openai-go"
 )
 
 type mockBedrockClient struct {
-	response *bedrockruntime.ConverseOutput
-	err      error
+	response  *bedrockruntime.ConverseOutput
+	err       error
+	events    []types.ConverseStreamOutput
+	streamErr error
 }
 
 func (m mockBedrockClient) Converse(
@@ -32,8 +43,109 @@ func (m mockBedrockClient) ConverseStream(
 	context.Context,
 	*bedrockruntime.ConverseStreamInput,
 	...func(*bedrockruntime.Options),
-) (*bedrockruntime.ConverseStreamOutput, error) {
-	return &bedrockruntime.ConverseStreamOutput{}, nil
+) (bedrock.EventStream, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	stream := newMockEventStream(m.events)
+	stream.err = m.streamErr
+	return stream, nil
+}
+
+type mockEventStream struct {
+	events chan types.ConverseStreamOutput
+	err    error
+}
+
+func newMockEventStream(events []types.ConverseStreamOutput) *mockEventStream {
+	stream := &mockEventStream{events: make(chan types.ConverseStreamOutput, len(events))}
+	for _, event := range events {
+		stream.events <- event
+	}
+	close(stream.events)
+	return stream
+}
+
+func (s *mockEventStream) Events() <-chan types.ConverseStreamOutput {
+	return s.events
+}
+
+func (s *mockEventStream) Close() error {
+	return nil
+}
+
+func (s *mockEventStream) Err() error {
+	return s.err
+}
+
+// slowBedrockClient streams deltas one at a time, waiting delay between
+// them, until its stream is closed or, if count is set, count deltas have
+// been sent. The deltas are text unless delta is set.
+type slowBedrockClient struct {
+	mockBedrockClient
+	delay  time.Duration
+	closed chan struct{}
+	delta  types.ContentBlockDelta
+	count  int
+}
+
+func (m slowBedrockClient) ConverseStream(
+	context.Context,
+	*bedrockruntime.ConverseStreamInput,
+	...func(*bedrockruntime.Options),
+) (bedrock.EventStream, error) {
+	stream := &slowEventStream{
+		events: make(chan types.ConverseStreamOutput),
+		done:   make(chan struct{}),
+		closed: m.closed,
+	}
+	var delta types.ContentBlockDelta = &types.ContentBlockDeltaMemberText{Value: "tick"}
+	if m.delta != nil {
+		delta = m.delta
+	}
+	go func() {
+		defer close(stream.events)
+		ticker := time.NewTicker(m.delay)
+		defer ticker.Stop()
+		for sent := 0; m.count == 0 || sent < m.count; sent++ {
+			select {
+			case <-stream.done:
+				return
+			case <-ticker.C:
+			}
+			select {
+			case <-stream.done:
+				return
+			case stream.events <- &types.ConverseStreamOutputMemberContentBlockDelta{
+				Value: types.ContentBlockDeltaEvent{
+					ContentBlockIndex: aws.Int32(0),
+					Delta:             delta,
+				},
+			}:
+			}
+		}
+	}()
+	return stream, nil
+}
+
+type slowEventStream struct {
+	events chan types.ConverseStreamOutput
+	done   chan struct{}
+	closed chan struct{}
+}
+
+func (s *slowEventStream) Events() <-chan types.ConverseStreamOutput {
+	return s.events
+}
+
+func (s *slowEventStream) Close() error {
+	close(s.done)
+	close(s.closed)
+	return nil
+}
+
+func (s *slowEventStream) Err() error {
+	return nil
 }
 
 func TestHandleChatCompletions(t *testing.T) {
@@ -84,6 +196,56 @@ func TestHandleChatCompletions(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "multiple choices",
+			method: http.MethodPost,
+			requestBody: convert.OpenAIRequest{
+				Model: "gpt-3.5-turbo",
+				N:     3,
+				Messages: []convert.OpenAIMessage{
+					{Role: "user", Content: "Hello"},
+				},
+			},
+			mockResponse: &bedrockruntime.ConverseOutput{
+				Output: &types.ConverseOutputMemberMessage{
+					Value: types.Message{
+						Content: []types.ContentBlock{
+							&types.ContentBlockMemberText{
+								Value: "Hi there!",
+							},
+						},
+					},
+				},
+			},
+			expectedCode: http.StatusOK,
+			validateResp: func(t *testing.T, w *httptest.ResponseRecorder) {
+				t.Helper()
+				var resp convert.OpenAIResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if len(resp.Choices) != 3 {
+					t.Fatalf("Expected 3 choices, got %d", len(resp.Choices))
+				}
+				for i, choice := range resp.Choices {
+					if choice.Index != i {
+						t.Errorf("Expected choice index %d, got %d", i, choice.Index)
+					}
+				}
+			},
+		},
+		{
+			name:   "too many choices",
+			method: http.MethodPost,
+			requestBody: convert.OpenAIRequest{
+				Model: "gpt-3.5-turbo",
+				N:     handler.DefaultMaxChoices + 1,
+				Messages: []convert.OpenAIMessage{
+					{Role: "user", Content: "Hello"},
+				},
+			},
+			expectedCode: http.StatusBadRequest,
+		},
 		{
 			name:         "invalid method",
 			method:       http.MethodGet,
@@ -95,6 +257,42 @@ func TestHandleChatCompletions(t *testing.T) {
 			requestBody:  "invalid json",
 			expectedCode: http.StatusBadRequest,
 		},
+		{
+			name:   "invalid message content",
+			method: http.MethodPost,
+			requestBody: map[string]interface{}{
+				"model": "gpt-3.5-turbo",
+				"messages": []interface{}{
+					map[string]interface{}{
+						"role": "user",
+						"content": []interface{}{
+							map[string]interface{}{"type": "file", "file": map[string]string{"file_id": "file-abc"}},
+						},
+					},
+				},
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "unsupported response format",
+			method: http.MethodPost,
+			requestBody: map[string]interface{}{
+				"model":           "gpt-3.5-turbo",
+				"response_format": map[string]string{"type": "xml"},
+				"messages":        []interface{}{map[string]string{"role": "user", "content": "Hello"}},
+			},
+			expectedCode: http.StatusBadRequest,
+			validateResp: func(t *testing.T, w *httptest.ResponseRecorder) {
+				t.Helper()
+				var resp handler.OpenAIErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode error response: %v", err)
+				}
+				if resp.Error.Type != "invalid_request_error" {
+					t.Errorf("Expected error type 'invalid_request_error', got %q", resp.Error.Type)
+				}
+			},
+		},
 		{
 			name:   "bedrock error",
 			method: http.MethodPost,
@@ -105,7 +303,20 @@ func TestHandleChatCompletions(t *testing.T) {
 				},
 			},
 			mockError:    &types.ValidationException{Message: aws.String("Invalid request")},
-			expectedCode: http.StatusInternalServerError,
+			expectedCode: http.StatusBadRequest,
+			validateResp: func(t *testing.T, w *httptest.ResponseRecorder) {
+				t.Helper()
+				var resp handler.OpenAIErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode error response: %v", err)
+				}
+				if resp.Error.Type != "invalid_request_error" {
+					t.Errorf("Expected error type 'invalid_request_error', got %q", resp.Error.Type)
+				}
+				if !strings.Contains(resp.Error.Message, "Invalid request") {
+					t.Errorf("Expected error message to contain 'Invalid request', got %q", resp.Error.Message)
+				}
+			},
 		},
 	}
 
@@ -126,7 +337,7 @@ func TestHandleChatCompletions(t *testing.T) {
 					response: tt.mockResponse,
 					err:      tt.mockError,
 				},
-				ModelMap: map[string]string{},
+				ModelMap: bedrock.ModelMap{},
 			}
 
 			handler.HandleChatCompletions(w, req)
@@ -141,3 +352,575 @@ func TestHandleChatCompletions(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleStreamedChatCompletions(t *testing.T) {
+	body, err := json.Marshal(convert.OpenAIRequest{
+		Model:  "gpt-3.5-turbo",
+		N:      2,
+		Stream: true,
+		Messages: []convert.OpenAIMessage{
+			{Role: "user", Content: "Hello"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode request body: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler := handler.Handler{
+		Converser: mockBedrockClient{
+			events: []types.ConverseStreamOutput{
+				&types.ConverseStreamOutputMemberContentBlockDelta{
+					Value: types.ContentBlockDeltaEvent{
+						ContentBlockIndex: aws.Int32(0),
+						Delta:             &types.ContentBlockDeltaMemberText{Value: "Hi"},
+					},
+				},
+			},
+		},
+		ModelMap: bedrock.ModelMap{},
+	}
+
+	handler.HandleChatCompletions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Expected content type 'text/event-stream', got %q", contentType)
+	}
+
+	if !strings.HasSuffix(w.Body.String(), "data: [DONE]\n\n") {
+		t.Errorf("Expected stream to end with [DONE], got %q", w.Body.String())
+	}
+
+	indexes := map[int64]bool{}
+	ids := map[string]bool{}
+	for _, chunk := range readChunks(t, w.Body.String()) {
+		ids[chunk.ID] = true
+		for _, choice := range chunk.Choices {
+			indexes[choice.Index] = true
+			if choice.Delta.Content != "Hi" {
+				t.Errorf("Expected content 'Hi', got %q", choice.Delta.Content)
+			}
+		}
+	}
+	if len(indexes) != 2 || !indexes[0] || !indexes[1] {
+		t.Errorf("Expected chunks for choices 0 and 1, got %v", indexes)
+	}
+	if len(ids) != 1 {
+		t.Errorf("Expected all chunks to share one id, got %v", ids)
+	}
+}
+
+func TestHandleStreamedChatCompletionsMidStreamError(t *testing.T) {
+	body, err := json.Marshal(convert.OpenAIRequest{
+		Model:    "gpt-3.5-turbo",
+		Stream:   true,
+		Messages: []convert.OpenAIMessage{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode request body: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h := handler.Handler{
+		Converser: mockBedrockClient{
+			events: []types.ConverseStreamOutput{
+				&types.ConverseStreamOutputMemberContentBlockDelta{
+					Value: types.ContentBlockDeltaEvent{
+						ContentBlockIndex: aws.Int32(0),
+						Delta:             &types.ContentBlockDeltaMemberText{Value: "Hi"},
+					},
+				},
+			},
+			streamErr: &types.ModelStreamErrorException{Message: aws.String("stream broke")},
+		},
+		ModelMap: bedrock.ModelMap{},
+	}
+	h.HandleChatCompletions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var data []string
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if d, ok := strings.CutPrefix(line, "data: "); ok {
+			data = append(data, d)
+		}
+	}
+	if len(data) != 2 {
+		t.Fatalf("Expected a chunk and an error event, got %q", data)
+	}
+
+	var resp handler.OpenAIErrorResponse
+	if err := json.Unmarshal([]byte(data[1]), &resp); err != nil {
+		t.Fatalf("Failed to decode error event: %v", err)
+	}
+	if !strings.Contains(resp.Error.Message, "stream broke") {
+		t.Errorf("Expected error message to contain 'stream broke', got %q", resp.Error.Message)
+	}
+	if resp.Error.Type != "server_error" {
+		t.Errorf("Expected error type 'server_error', got %q", resp.Error.Type)
+	}
+}
+
+func readChunks(t *testing.T, body string) []openai.ChatCompletionChunk {
+	t.Helper()
+	var chunks []openai.ChatCompletionChunk
+	for _, line := range strings.Split(body, "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk openai.ChatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("Failed to decode chunk %q: %v", data, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+func TestHandleStreamedChatCompletionsUsage(t *testing.T) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model":          "gpt-3.5-turbo",
+		"stream":         true,
+		"stream_options": map[string]bool{"include_usage": true},
+		"messages": []map[string]string{
+			{"role": "user", "content": "Hello"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode request body: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler := handler.Handler{
+		Converser: mockBedrockClient{
+			events: []types.ConverseStreamOutput{
+				&types.ConverseStreamOutputMemberContentBlockDelta{
+					Value: types.ContentBlockDeltaEvent{
+						ContentBlockIndex: aws.Int32(0),
+						Delta:             &types.ContentBlockDeltaMemberText{Value: "Hi"},
+					},
+				},
+				&types.ConverseStreamOutputMemberMessageStop{
+					Value: types.MessageStopEvent{StopReason: types.StopReasonEndTurn},
+				},
+				&types.ConverseStreamOutputMemberMetadata{
+					Value: types.ConverseStreamMetadataEvent{
+						Usage: &types.TokenUsage{InputTokens: aws.Int32(3), OutputTokens: aws.Int32(1)},
+					},
+				},
+			},
+		},
+		ModelMap: bedrock.ModelMap{},
+	}
+
+	handler.HandleChatCompletions(w, req)
+
+	chunks := readChunks(t, w.Body.String())
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d", len(chunks))
+	}
+	last := chunks[len(chunks)-1]
+	if len(last.Choices) != 0 {
+		t.Errorf("Expected usage chunk without choices, got %d", len(last.Choices))
+	}
+	if last.Usage.PromptTokens != 3 || last.Usage.CompletionTokens != 1 || last.Usage.TotalTokens != 4 {
+		t.Errorf("Unexpected usage %+v", last.Usage)
+	}
+}
+
+func TestHandleChatCompletionsBedrockErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode int
+		expectedType string
+		expectedErr  string
+	}{
+		{
+			name:         "access denied",
+			err:          &types.AccessDeniedException{Message: aws.String("denied")},
+			expectedCode: http.StatusForbidden,
+			expectedType: "permission_error",
+			expectedErr:  "access_denied",
+		},
+		{
+			name:         "resource not found",
+			err:          &types.ResourceNotFoundException{Message: aws.String("no such model")},
+			expectedCode: http.StatusNotFound,
+			expectedType: "invalid_request_error",
+			expectedErr:  "model_not_found",
+		},
+		{
+			name:         "throttling",
+			err:          &types.ThrottlingException{Message: aws.String("slow down")},
+			expectedCode: http.StatusTooManyRequests,
+			expectedType: "rate_limit_error",
+			expectedErr:  "rate_limit_exceeded",
+		},
+		{
+			name:         "service quota exceeded",
+			err:          &types.ServiceQuotaExceededException{Message: aws.String("quota")},
+			expectedCode: http.StatusTooManyRequests,
+			expectedType: "rate_limit_error",
+			expectedErr:  "quota_exceeded",
+		},
+		{
+			name:         "model not ready",
+			err:          &types.ModelNotReadyException{Message: aws.String("warming up")},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedType: "server_error",
+			expectedErr:  "model_not_ready",
+		},
+		{
+			name:         "service unavailable",
+			err:          fmt.Errorf("%w: failed to invoke bedrock", &types.ServiceUnavailableException{Message: aws.String("down")}),
+			expectedCode: http.StatusServiceUnavailable,
+			expectedType: "server_error",
+			expectedErr:  "service_unavailable",
+		},
+		{
+			name:         "unknown error",
+			err:          errors.New("boom"),
+			expectedCode: http.StatusInternalServerError,
+			expectedType: "server_error",
+		},
+	}
+
+	for _, tt := range tests {
+		for _, stream := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s stream=%t", tt.name, stream), func(t *testing.T) {
+				body, err := json.Marshal(convert.OpenAIRequest{
+					Model:    "gpt-3.5-turbo",
+					Stream:   stream,
+					Messages: []convert.OpenAIMessage{{Role: "user", Content: "Hello"}},
+				})
+				if err != nil {
+					t.Fatalf("Failed to encode request body: %v", err)
+				}
+
+				req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+				w := httptest.NewRecorder()
+
+				h := handler.Handler{
+					Converser: mockBedrockClient{err: tt.err},
+					ModelMap:  bedrock.ModelMap{},
+				}
+				h.HandleChatCompletions(w, req)
+
+				if w.Code != tt.expectedCode {
+					t.Errorf("Expected status code %d, got %d", tt.expectedCode, w.Code)
+				}
+				var resp handler.OpenAIErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode error response: %v", err)
+				}
+				if resp.Error.Type != tt.expectedType {
+					t.Errorf("Expected error type %q, got %q", tt.expectedType, resp.Error.Type)
+				}
+				code := ""
+				if resp.Error.Code != nil {
+					code = *resp.Error.Code
+				}
+				if code != tt.expectedErr {
+					t.Errorf("Expected error code %q, got %q", tt.expectedErr, code)
+				}
+			})
+		}
+	}
+}
+
+func TestHandleStreamedChatCompletionsClientDisconnect(t *testing.T) {
+	body, err := json.Marshal(convert.OpenAIRequest{
+		Model:    "gpt-3.5-turbo",
+		Stream:   true,
+		Messages: []convert.OpenAIMessage{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode request body: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body)).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	closed := make(chan struct{})
+	h := handler.Handler{
+		Converser: slowBedrockClient{delay: 10 * time.Millisecond, closed: closed},
+		ModelMap:  bedrock.ModelMap{},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.HandleChatCompletions(w, req)
+	}()
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the Bedrock stream to be closed after the client disconnected")
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the handler to return after the client disconnected")
+	}
+
+	if strings.Contains(w.Body.String(), "[DONE]") {
+		t.Errorf("Expected no [DONE] for an abandoned stream, got %q", w.Body.String())
+	}
+}
+
+// lockedBuffer collects the logs of streams that outlive their handler.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestHandleStreamedChatCompletionsAbandonedStreamUsage(t *testing.T) {
+	logs := &lockedBuffer{}
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+	abandoned := expvar.Get("abandoned_streams").(*expvar.Int).Value()
+
+	body, err := json.Marshal(convert.OpenAIRequest{
+		Model:    "gpt-3.5-turbo",
+		Stream:   true,
+		Messages: []convert.OpenAIMessage{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode request body: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body)).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	closed := make(chan struct{})
+	h := handler.Handler{
+		Converser: slowBedrockClient{delay: 10 * time.Millisecond, closed: closed},
+		ModelMap:  bedrock.ModelMap{},
+	}
+	time.AfterFunc(50*time.Millisecond, cancel)
+	h.HandleChatCompletions(w, req)
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the Bedrock stream to be closed after the client disconnected")
+	}
+
+	var entry struct {
+		Msg    string `json:"msg"`
+		Chunks int    `json:"chunks"`
+		Tokens int    `json:"estimated_completion_tokens"`
+	}
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to decode log line %q: %v", line, err)
+		}
+		if entry.Msg == "Abandoned Bedrock stream" {
+			break
+		}
+	}
+	if entry.Msg != "Abandoned Bedrock stream" {
+		t.Fatalf("Expected the abandoned stream to be logged, got %q", logs.String())
+	}
+	if entry.Chunks == 0 || entry.Tokens == 0 {
+		t.Errorf("Expected the streamed chunks and estimated tokens to be logged, got %+v", entry)
+	}
+	if got := expvar.Get("abandoned_streams").(*expvar.Int).Value(); got <= abandoned {
+		t.Errorf("Expected the abandoned stream counter to increase from %d, got %d", abandoned, got)
+	}
+}
+
+func TestHandleStreamedChatCompletionsTimeouts(t *testing.T) {
+	tests := []struct {
+		name          string
+		handler       handler.Handler
+		expectedError string
+	}{
+		{
+			name: "Idle stream",
+			handler: handler.Handler{
+				StreamIdleTimeout: 50 * time.Millisecond,
+				HeartbeatInterval: 10 * time.Millisecond,
+			},
+			expectedError: "idle timeout",
+		},
+		{
+			name: "Stream exceeds maximum duration",
+			handler: handler.Handler{
+				MaxStreamDuration: 50 * time.Millisecond,
+				HeartbeatInterval: 10 * time.Millisecond,
+			},
+			expectedError: "maximum duration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(convert.OpenAIRequest{
+				Model:    "gpt-3.5-turbo",
+				Stream:   true,
+				Messages: []convert.OpenAIMessage{{Role: "user", Content: "Hello"}},
+			})
+			if err != nil {
+				t.Fatalf("Failed to encode request body: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			closed := make(chan struct{})
+			delay := 20 * time.Millisecond
+			if tt.handler.StreamIdleTimeout > 0 {
+				delay = time.Hour
+			}
+			h := tt.handler
+			h.Converser = slowBedrockClient{delay: delay, closed: closed}
+			h.ModelMap = bedrock.ModelMap{}
+			h.HandleChatCompletions(w, req)
+
+			select {
+			case <-closed:
+			case <-time.After(time.Second):
+				t.Fatal("Expected the Bedrock stream to be closed")
+			}
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), ": keep-alive\n\n") {
+				t.Errorf("Expected heartbeat comments, got %q", w.Body.String())
+			}
+
+			lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+			last, ok := strings.CutPrefix(lines[len(lines)-1], "data: ")
+			if !ok {
+				t.Fatalf("Expected the stream to end with an error event, got %q", w.Body.String())
+			}
+			var resp handler.OpenAIErrorResponse
+			if err := json.Unmarshal([]byte(last), &resp); err != nil {
+				t.Fatalf("Failed to decode error event: %v", err)
+			}
+			if !strings.Contains(resp.Error.Message, tt.expectedError) {
+				t.Errorf("Expected error message to contain %q, got %q", tt.expectedError, resp.Error.Message)
+			}
+		})
+	}
+}
+
+func TestHandleStreamedChatCompletionsIdleTimeoutWhileThinking(t *testing.T) {
+	body, err := json.Marshal(convert.OpenAIRequest{
+		Model:    "gpt-3.5-turbo",
+		Stream:   true,
+		Messages: []convert.OpenAIMessage{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode request body: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h := handler.Handler{
+		Converser: slowBedrockClient{
+			delay:  10 * time.Millisecond,
+			closed: make(chan struct{}),
+			delta: &types.ContentBlockDeltaMemberReasoningContent{
+				Value: &types.ReasoningContentBlockDeltaMemberText{Value: "Hmm."},
+			},
+			count: 15,
+		},
+		ModelMap:          bedrock.ModelMap{},
+		StreamIdleTimeout: 50 * time.Millisecond,
+	}
+	h.HandleChatCompletions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if strings.Contains(w.Body.String(), "idle timeout") || !strings.HasSuffix(w.Body.String(), "data: [DONE]\n\n") {
+		t.Errorf("Expected the stream to outlast the idle timeout while thinking, got %q", w.Body.String())
+	}
+}
+
+func TestHandleChatCompletionsStrictModelMap(t *testing.T) {
+	h := handler.Handler{
+		Converser: mockBedrockClient{response: &bedrockruntime.ConverseOutput{
+			Output: &types.ConverseOutputMemberMessage{Value: types.Message{
+				Role:    types.ConversationRoleAssistant,
+				Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "Hi"}},
+			}},
+			StopReason: types.StopReasonEndTurn,
+			Usage:      &types.TokenUsage{InputTokens: aws.Int32(1), OutputTokens: aws.Int32(1), TotalTokens: aws.Int32(2)},
+		}},
+		ModelMap: bedrock.ModelMap{
+			Aliases: map[string]bedrock.ModelConfig{"gpt-4o": {ModelID: "anthropic.claude"}},
+			Strict:  true,
+		},
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"Mapped", `{"model": "gpt-4o", "messages": [{"role": "user", "content": "Hello"}]}`, http.StatusOK},
+		{"Unmapped", `{"model": "junk", "messages": [{"role": "user", "content": "Hello"}]}`, http.StatusNotFound},
+		{"Unmapped stream", `{"model": "junk", "stream": true, "messages": [{"role": "user", "content": "Hello"}]}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			h.HandleChatCompletions(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusNotFound {
+				return
+			}
+			var resp handler.OpenAIErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode error response: %v", err)
+			}
+			if resp.Error.Code == nil || *resp.Error.Code != "model_not_found" {
+				t.Errorf("Expected code 'model_not_found', got %v", resp.Error.Code)
+			}
+			if resp.Error.Param == nil || *resp.Error.Param != "model" {
+				t.Errorf("Expected param 'model', got %v", resp.Error.Param)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"expvar"
+	"net/http"
+)
+
+// Metrics are published with expvar and served on /debug/vars.
+var (
+	abandonedStreams      = expvar.NewInt("abandoned_streams")
+	abandonedStreamTokens = expvar.NewInt("abandoned_stream_estimated_completion_tokens")
+)
+
+// HandleMetrics serves the published metrics as JSON.
+func (h Handler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	expvar.Handler().ServeHTTP(w, r)
+}
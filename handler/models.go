@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+)
+
+type OpenAIModelList struct {
+	Object string        `json:"object"`
+	Data   []OpenAIModel `json:"data"`
+}
+
+type OpenAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// HandleModels serves GET /v1/models and GET /v1/models/{id}. It lists the
+// aliases in the model map and, when a catalog is configured, the Bedrock
+// models the account can use.
+func (h Handler) HandleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errorTypeInvalidRequest, "method_not_allowed", "", "Method not allowed")
+		return
+	}
+
+	models := h.listModels(r.Context())
+
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v1/models"), "/")
+	if id == "" {
+		writeJSON(w, OpenAIModelList{Object: "list", Data: models})
+		return
+	}
+
+	for _, model := range models {
+		if model.ID == id {
+			writeJSON(w, model)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, errorTypeInvalidRequest, "model_not_found", "model", "The model '"+id+"' does not exist")
+}
+
+func (h Handler) listModels(ctx context.Context) []OpenAIModel {
+	models := []OpenAIModel{}
+	seen := map[string]bool{}
+	for _, name := range h.ModelMap.Names() {
+		seen[name] = true
+		models = append(models, OpenAIModel{
+			ID:      name,
+			Object:  "model",
+			OwnedBy: bedrock.ModelProvider(h.ModelMap.BedrockModelID(name)),
+		})
+	}
+
+	if h.Catalog == nil {
+		return models
+	}
+	catalog, err := h.Catalog.Models(ctx)
+	if err != nil {
+		slog.Warn("Failed to list Bedrock models", "error", err)
+		return models
+	}
+	for _, model := range catalog {
+		if seen[model.ID] {
+			continue
+		}
+		if _, err := h.ModelMap.Resolve(model.ID); err != nil {
+			continue
+		}
+		var created int64
+		if !model.Created.IsZero() {
+			created = model.Created.Unix()
+		}
+		models = append(models, OpenAIModel{
+			ID:      model.ID,
+			Object:  "model",
+			Created: created,
+			OwnedBy: model.Provider,
+		})
+	}
+	return models
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,129 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/DefangLabs/bedrock-sidecar/handler"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	bedrockapi "github.com/aws/aws-sdk-go-v2/service/bedrock"
+	"github.com/aws/aws-sdk-go-v2/service/bedrock/types"
+)
+
+type mockCatalogLister struct {
+	models []types.FoundationModelSummary
+}
+
+func (m mockCatalogLister) ListFoundationModels(
+	context.Context,
+	*bedrockapi.ListFoundationModelsInput,
+	...func(*bedrockapi.Options),
+) (*bedrockapi.ListFoundationModelsOutput, error) {
+	return &bedrockapi.ListFoundationModelsOutput{ModelSummaries: m.models}, nil
+}
+
+func (m mockCatalogLister) ListInferenceProfiles(
+	context.Context,
+	*bedrockapi.ListInferenceProfilesInput,
+	...func(*bedrockapi.Options),
+) (*bedrockapi.ListInferenceProfilesOutput, error) {
+	return &bedrockapi.ListInferenceProfilesOutput{}, nil
+}
+
+func TestHandleModels(t *testing.T) {
+	h := handler.Handler{
+		ModelMap: bedrock.ModelMap{Aliases: map[string]bedrock.ModelConfig{
+			"gpt-4o": {ModelID: "anthropic.claude-3-5-sonnet-20241022-v2:0"},
+		}},
+		Catalog: bedrock.NewCatalog(mockCatalogLister{
+			models: []types.FoundationModelSummary{{
+				ModelId:                 aws.String("meta.llama3-8b-instruct-v1:0"),
+				ProviderName:            aws.String("Meta"),
+				InputModalities:         []types.ModelModality{types.ModelModalityText},
+				OutputModalities:        []types.ModelModality{types.ModelModalityText},
+				InferenceTypesSupported: []types.InferenceType{types.InferenceTypeOnDemand},
+			}},
+		}, time.Hour),
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedIDs    []string
+		expectedOwner  string
+	}{
+		{
+			name:           "List",
+			path:           "/v1/models",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []string{"gpt-4o", "meta.llama3-8b-instruct-v1:0"},
+		},
+		{
+			name:           "Alias",
+			path:           "/v1/models/gpt-4o",
+			expectedStatus: http.StatusOK,
+			expectedOwner:  "anthropic",
+		},
+		{
+			name:           "Catalog model",
+			path:           "/v1/models/meta.llama3-8b-instruct-v1:0",
+			expectedStatus: http.StatusOK,
+			expectedOwner:  "meta",
+		},
+		{
+			name:           "Unknown model",
+			path:           "/v1/models/gpt-5",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			h.HandleModels(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			switch {
+			case tt.expectedIDs != nil:
+				var list handler.OpenAIModelList
+				if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if list.Object != "list" || len(list.Data) != len(tt.expectedIDs) {
+					t.Fatalf("Expected %d models, got %+v", len(tt.expectedIDs), list)
+				}
+				for i, id := range tt.expectedIDs {
+					if list.Data[i].ID != id {
+						t.Errorf("Expected model %d to be %q, got %q", i, id, list.Data[i].ID)
+					}
+				}
+			case tt.expectedOwner != "":
+				var model handler.OpenAIModel
+				if err := json.Unmarshal(w.Body.Bytes(), &model); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if model.OwnedBy != tt.expectedOwner {
+					t.Errorf("Expected owner %q, got %q", tt.expectedOwner, model.OwnedBy)
+				}
+			default:
+				var resp handler.OpenAIErrorResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("Failed to decode error response: %v", err)
+				}
+				if resp.Error.Code == nil || *resp.Error.Code != "model_not_found" {
+					t.Errorf("Expected code 'model_not_found', got %v", resp.Error.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/DefangLabs/bedrock-sidecar/convert"
+	"github.com/openai/openai-go"
+)
+
+type OllamaErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type OllamaTagsResponse struct {
+	Models []OllamaModel `json:"models"`
+}
+
+type OllamaModel struct {
+	Name       string             `json:"name"`
+	Model      string             `json:"model"`
+	ModifiedAt time.Time          `json:"modified_at"`
+	Size       int64              `json:"size"`
+	Digest     string             `json:"digest"`
+	Details    OllamaModelDetails `json:"details"`
+}
+
+type OllamaModelDetails struct {
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+type OllamaShowRequest struct {
+	Model string `json:"model"`
+	// Name is the field used by older Ollama clients.
+	Name string `json:"name"`
+}
+
+type OllamaShowResponse struct {
+	Modelfile    string                 `json:"modelfile"`
+	Parameters   string                 `json:"parameters"`
+	Template     string                 `json:"template"`
+	Details      OllamaModelDetails     `json:"details"`
+	ModelInfo    map[string]interface{} `json:"model_info"`
+	Capabilities []string               `json:"capabilities"`
+}
+
+func writeOllamaError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(OllamaErrorResponse{Error: message}); err != nil {
+		slog.Error("Failed to encode error response", "error", err)
+	}
+}
+
+func writeOllamaBedrockError(w http.ResponseWriter, err error) {
+	status, _, _ := classifyBedrockError(err)
+	writeOllamaError(w, status, err.Error())
+}
+
+func (h Handler) HandleOllamaChat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOllamaError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var ollamaReq convert.OllamaChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&ollamaReq); err != nil {
+		writeOllamaError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	slog.Debug("Received", "request", ollamaReq)
+
+	openAIReq, err := ollamaReq.ToOpenAIRequest()
+	if err != nil {
+		writeOllamaError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.serveOllama(r.Context(), w, openAIReq, func(resp convert.OllamaChatResponse) interface{} {
+		return resp
+	})
+}
+
+func (h Handler) HandleOllamaGenerate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOllamaError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var ollamaReq convert.OllamaGenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&ollamaReq); err != nil {
+		writeOllamaError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	slog.Debug("Received", "request", ollamaReq)
+
+	openAIReq, err := ollamaReq.ToOpenAIRequest()
+	if err != nil {
+		writeOllamaError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.serveOllama(r.Context(), w, openAIReq, func(resp convert.OllamaChatResponse) interface{} {
+		return resp.ToGenerate()
+	})
+}
+
+// serveOllama runs a converted Ollama request. encode reshapes each chat
+// response for the endpoint being served.
+func (h Handler) serveOllama(
+	ctx context.Context,
+	w http.ResponseWriter,
+	openAIReq convert.OpenAIRequest,
+	encode func(convert.OllamaChatResponse) interface{},
+) {
+	start := time.Now()
+	if openAIReq.Stream {
+		h.handleStreamedChatCompletion(ctx, w, openAIReq, ollamaChunkEncoder(openAIReq, start, encode))
+		return
+	}
+
+	bedrockReq, err := convert.ToBedrockRequest(ctx, h.ModelMap, openAIReq)
+	if err != nil {
+		writeOllamaError(w, conversionErrorStatus(err), err.Error())
+		return
+	}
+	slog.Debug("Converted", "request", bedrockReq)
+
+	bedrockResp, err := h.Converser.Converse(ctx, &bedrockReq)
+	if err != nil {
+		slog.Error("Failed to invoke Bedrock Converse", "error", err)
+		writeOllamaBedrockError(w, err)
+		return
+	}
+	slog.Debug("Received", "response", bedrockResp)
+
+	resp := convert.ToOllamaChatResponse(convert.ToOpenAIResponse(bedrockResp, openAIReq.Model))
+	resp.TotalDuration = time.Since(start).Nanoseconds()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(encode(resp)); err != nil {
+		slog.Error("Failed to encode response", "error", err)
+	}
+}
+
+// ollamaChunkEncoder streams chunks as Ollama chat responses, one per line.
+// encode reshapes each response for the endpoint being served.
+func ollamaChunkEncoder(
+	openAIReq convert.OpenAIRequest,
+	start time.Time,
+	encode func(convert.OllamaChatResponse) interface{},
+) chunkEncoder {
+	stream := convert.NewOllamaStream(openAIReq.Model)
+	return chunkEncoder{
+		ndjson: true,
+		chunk: func(out *sseWriter, chunk openai.ChatCompletionChunk) error {
+			resp, ok := stream.Chunk(chunk)
+			if !ok {
+				return nil
+			}
+			return out.writeLine(encode(resp))
+		},
+		done: func(out *sseWriter, _ convert.StreamIdentity, usage convert.Usage) error {
+			final := stream.Done(usage)
+			final.TotalDuration = time.Since(start).Nanoseconds()
+			return out.writeLine(encode(final))
+		},
+		fail: func(out *sseWriter, err error) error {
+			return out.writeLine(OllamaErrorResponse{Error: err.Error()})
+		},
+		conversionError: func(w http.ResponseWriter, err error) {
+			writeOllamaError(w, conversionErrorStatus(err), err.Error())
+		},
+		bedrockError: writeOllamaBedrockError,
+	}
+}
+
+// HandleOllamaTags lists the mapped models as if they were installed.
+func (h Handler) HandleOllamaTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeOllamaError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	resp := OllamaTagsResponse{Models: []OllamaModel{}}
+	for _, name := range h.ModelMap.Names() {
+		modelID := h.ModelMap.BedrockModelID(name)
+		digest := sha256.Sum256([]byte(modelID))
+		resp.Models = append(resp.Models, OllamaModel{
+			Name:    name,
+			Model:   name,
+			Digest:  hex.EncodeToString(digest[:]),
+			Details: ollamaModelDetails(modelID),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("Failed to encode response", "error", err)
+	}
+}
+
+// HandleOllamaShow describes a model. Like the chat endpoints it accepts
+// any name, since unmapped names are passed to Bedrock as model IDs, unless
+// the model map is strict.
+func (h Handler) HandleOllamaShow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOllamaError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var showReq OllamaShowRequest
+	if err := json.NewDecoder(r.Body).Decode(&showReq); err != nil {
+		writeOllamaError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	name := showReq.Model
+	if name == "" {
+		name = showReq.Name
+	}
+	if name == "" {
+		writeOllamaError(w, http.StatusBadRequest, "model is required")
+		return
+	}
+
+	config, err := h.ModelMap.Resolve(name)
+	if err != nil {
+		writeOllamaError(w, http.StatusNotFound, fmt.Sprintf("model '%s' not found", name))
+		return
+	}
+	modelID := config.ModelID
+	resp := OllamaShowResponse{
+		Details: ollamaModelDetails(modelID),
+		ModelInfo: map[string]interface{}{
+			"general.architecture": bedrock.ModelProvider(modelID),
+			"bedrock.model_id":     modelID,
+		},
+		Capabilities: []string{"completion", "tools"},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("Failed to encode response", "error", err)
+	}
+}
+
+func ollamaModelDetails(modelID string) OllamaModelDetails {
+	family := bedrock.ModelProvider(modelID)
+	return OllamaModelDetails{
+		Format:   "bedrock",
+		Family:   family,
+		Families: []string{family},
+	}
+}
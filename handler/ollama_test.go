@@ -0,0 +1,211 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/DefangLabs/bedrock-sidecar/convert"
+	"github.com/DefangLabs/bedrock-sidecar/handler"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+func TestHandleOllamaChatStream(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(`{
+		"model": "llama3",
+		"messages": [{"role": "user", "content": "Hello"}]
+	}`))
+	w := httptest.NewRecorder()
+
+	h := handler.Handler{
+		Converser: mockBedrockClient{
+			events: []types.ConverseStreamOutput{
+				&types.ConverseStreamOutputMemberMessageStart{Value: types.MessageStartEvent{Role: types.ConversationRoleAssistant}},
+				&types.ConverseStreamOutputMemberContentBlockDelta{
+					Value: types.ContentBlockDeltaEvent{
+						ContentBlockIndex: aws.Int32(0),
+						Delta:             &types.ContentBlockDeltaMemberText{Value: "Hi"},
+					},
+				},
+				&types.ConverseStreamOutputMemberContentBlockDelta{
+					Value: types.ContentBlockDeltaEvent{
+						ContentBlockIndex: aws.Int32(0),
+						Delta:             &types.ContentBlockDeltaMemberText{Value: " there"},
+					},
+				},
+				&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: types.StopReasonMaxTokens}},
+				&types.ConverseStreamOutputMemberMetadata{
+					Value: types.ConverseStreamMetadataEvent{
+						Usage: &types.TokenUsage{InputTokens: aws.Int32(5), OutputTokens: aws.Int32(2)},
+					},
+				},
+			},
+		},
+		ModelMap: bedrock.ModelMap{},
+	}
+	h.HandleOllamaChat(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Errorf("Expected content type application/x-ndjson, got %q", contentType)
+	}
+
+	var responses []convert.OllamaChatResponse
+	for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+		var resp convert.OllamaChatResponse
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("Failed to decode line %q: %v", line, err)
+		}
+		responses = append(responses, resp)
+	}
+	if len(responses) != 3 {
+		t.Fatalf("Expected 3 responses, got %d: %q", len(responses), w.Body.String())
+	}
+
+	if responses[0].Message.Content != "Hi" || responses[1].Message.Content != " there" {
+		t.Errorf("Unexpected content: %q, %q", responses[0].Message.Content, responses[1].Message.Content)
+	}
+	if responses[0].Done || responses[1].Done {
+		t.Error("Expected only the last response to be done")
+	}
+
+	final := responses[2]
+	if !final.Done {
+		t.Error("Expected the last response to be done")
+	}
+	if final.DoneReason != "length" {
+		t.Errorf("Expected done_reason 'length', got %q", final.DoneReason)
+	}
+	if final.PromptEvalCount != 5 || final.EvalCount != 2 {
+		t.Errorf("Expected counts 5 and 2, got %d and %d", final.PromptEvalCount, final.EvalCount)
+	}
+	if final.TotalDuration <= 0 {
+		t.Errorf("Expected a total_duration, got %d", final.TotalDuration)
+	}
+}
+
+func TestHandleOllamaGenerate(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		client         mockBedrockClient
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Buffered response",
+			body: `{"model": "llama3", "prompt": "Hello", "stream": false}`,
+			client: mockBedrockClient{
+				response: &bedrockruntime.ConverseOutput{
+					Output: &types.ConverseOutputMemberMessage{
+						Value: types.Message{
+							Role:    types.ConversationRoleAssistant,
+							Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "Hi!"}},
+						},
+					},
+					StopReason: types.StopReasonEndTurn,
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"response":"Hi!"`,
+		},
+		{
+			name:           "Unsupported suffix",
+			body:           `{"model": "llama3", "prompt": "def f(", "suffix": "return x"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"error":`,
+		},
+		{
+			name:           "Bedrock error",
+			body:           `{"model": "llama3", "prompt": "Hello", "stream": false}`,
+			client:         mockBedrockClient{err: &types.ThrottlingException{Message: aws.String("slow down")}},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   `"error":"ThrottlingException: slow down"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/generate", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h := handler.Handler{Converser: tt.client, ModelMap: bedrock.ModelMap{}}
+			h.HandleOllamaGenerate(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %q, got %q", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleOllamaTags(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+	w := httptest.NewRecorder()
+
+	h := handler.Handler{
+		ModelMap: bedrock.ModelMap{Aliases: map[string]bedrock.ModelConfig{
+			"llama3":  {ModelID: "meta.llama3-8b-instruct-v1:0"},
+			"claude3": {ModelID: "us.anthropic.claude-3-haiku-20240307-v1:0"},
+		}},
+	}
+	h.HandleOllamaTags(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp handler.OllamaTagsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Models) != 2 {
+		t.Fatalf("Expected 2 models, got %d", len(resp.Models))
+	}
+	if resp.Models[0].Name != "claude3" || resp.Models[1].Name != "llama3" {
+		t.Errorf("Expected models in name order, got %q and %q", resp.Models[0].Name, resp.Models[1].Name)
+	}
+	if resp.Models[0].Details.Family != "anthropic" {
+		t.Errorf("Expected family 'anthropic', got %q", resp.Models[0].Details.Family)
+	}
+}
+
+func TestHandleOllamaShow(t *testing.T) {
+	body, err := json.Marshal(handler.OllamaShowRequest{Name: "llama3"})
+	if err != nil {
+		t.Fatalf("Failed to encode request body: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/show", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h := handler.Handler{
+		ModelMap: bedrock.ModelMap{Aliases: map[string]bedrock.ModelConfig{"llama3": {ModelID: "meta.llama3-8b-instruct-v1:0"}}},
+	}
+	h.HandleOllamaShow(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp handler.OllamaShowResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.ModelInfo["bedrock.model_id"] != "meta.llama3-8b-instruct-v1:0" {
+		t.Errorf("Expected the Bedrock model ID in model_info, got %v", resp.ModelInfo)
+	}
+	if resp.Details.Family != "meta" {
+		t.Errorf("Expected family 'meta', got %q", resp.Details.Family)
+	}
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/DefangLabs/bedrock-sidecar/convert"
+	"github.com/openai/openai-go"
+)
+
+const DefaultMaxStoredResponses = 1000
+
+// ResponseStore keeps recent responses in memory for GET /v1/responses/{id}
+// and previous_response_id, dropping the oldest once it is full.
+type ResponseStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	responses  map[string]convert.StoredResponse
+	order      []string
+}
+
+func NewResponseStore(maxEntries int) *ResponseStore {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxStoredResponses
+	}
+	return &ResponseStore{maxEntries: maxEntries, responses: map[string]convert.StoredResponse{}}
+}
+
+func (s *ResponseStore) Get(id string) (convert.StoredResponse, bool) {
+	if s == nil {
+		return convert.StoredResponse{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.responses[id]
+	return stored, ok
+}
+
+func (s *ResponseStore) Put(stored convert.StoredResponse) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := stored.Response.ID
+	if _, ok := s.responses[id]; !ok {
+		s.order = append(s.order, id)
+	}
+	s.responses[id] = stored
+	for len(s.order) > s.maxEntries {
+		delete(s.responses, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// HandleResponses serves POST /v1/responses and GET /v1/responses/{id}.
+// A response is run as a chat completion of its conversation.
+func (h Handler) HandleResponses(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v1/responses"), "/")
+	switch {
+	case r.Method == http.MethodGet && id != "":
+		stored, ok := h.Responses.Get(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, errorTypeInvalidRequest, "not_found", "", "Response with id '"+id+"' not found.")
+			return
+		}
+		writeJSON(w, stored.Response)
+		return
+	case r.Method != http.MethodPost || id != "":
+		writeError(w, http.StatusMethodNotAllowed, errorTypeInvalidRequest, "method_not_allowed", "", "Method not allowed")
+		return
+	}
+
+	ctx := r.Context()
+	var responsesReq convert.ResponsesRequest
+	if err := json.NewDecoder(r.Body).Decode(&responsesReq); err != nil {
+		writeInvalidRequest(w, "", "Invalid request body")
+		return
+	}
+	slog.Debug("Received", "request", responsesReq)
+
+	var history []convert.ResponseItem
+	if responsesReq.PreviousResponseID != "" {
+		previous, ok := h.Responses.Get(responsesReq.PreviousResponseID)
+		if !ok {
+			writeError(w, http.StatusNotFound, errorTypeInvalidRequest, "previous_response_not_found", "previous_response_id",
+				"Previous response with id '"+responsesReq.PreviousResponseID+"' not found.")
+			return
+		}
+		history = previous.Conversation
+	}
+
+	openAIReq, err := responsesReq.ToOpenAIRequest(history)
+	if err != nil {
+		writeInvalidRequest(w, "input", err.Error())
+		return
+	}
+
+	resp := convert.NewResponseObject(responsesReq)
+	conversation := append(append([]convert.ResponseItem{}, history...), responsesReq.Input...)
+	store := func(resp convert.ResponseObject) {
+		if responsesReq.Stored() {
+			h.Responses.Put(convert.StoredResponse{
+				Response:     resp,
+				Conversation: append(conversation, resp.Output...),
+			})
+		}
+	}
+
+	if openAIReq.Stream {
+		h.handleStreamedChatCompletion(ctx, w, openAIReq, responseEncoder(convert.NewResponseStream(resp), store))
+		return
+	}
+
+	bedrockReq, err := convert.ToBedrockRequest(r.Context(), h.ModelMap, openAIReq)
+	if err != nil {
+		writeConversionError(w, err)
+		return
+	}
+	slog.Debug("Converted", "request", bedrockReq)
+
+	openAIResp, err := h.converse(ctx, bedrockReq, openAIReq)
+	if err != nil {
+		slog.Error("Failed to invoke Bedrock Converse", "error", err)
+		writeBedrockError(w, err)
+		return
+	}
+
+	resp = convert.CompleteResponse(resp, openAIResp)
+	store(resp)
+	writeJSON(w, resp)
+}
+
+// responseEncoder streams a response as typed events, storing it once it
+// has completed or failed.
+func responseEncoder(stream *convert.ResponseStream, store func(convert.ResponseObject)) chunkEncoder {
+	writeEvents := func(sse *sseWriter, events []convert.ResponseStreamEvent) error {
+		for _, event := range events {
+			if err := sse.writeEvent(event.Type, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return chunkEncoder{
+		chunk: func(sse *sseWriter, chunk openai.ChatCompletionChunk) error {
+			return writeEvents(sse, stream.Chunk(chunk))
+		},
+		done: func(sse *sseWriter, _ convert.StreamIdentity, usage convert.Usage) error {
+			events := stream.Done(usage)
+			store(stream.Response())
+			return writeEvents(sse, events)
+		},
+		fail: func(sse *sseWriter, err error) error {
+			_, _, code := classifyBedrockError(err)
+			if code == "" {
+				code = "server_error"
+			}
+			events := stream.Fail(code, err.Error())
+			store(stream.Response())
+			return writeEvents(sse, events)
+		},
+	}
+}
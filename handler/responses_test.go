@@ -0,0 +1,135 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/DefangLabs/bedrock-sidecar/convert"
+	"github.com/DefangLabs/bedrock-sidecar/handler"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+func TestHandleResponses(t *testing.T) {
+	h := handler.Handler{
+		Converser: mockBedrockClient{
+			response: &bedrockruntime.ConverseOutput{
+				Output: &types.ConverseOutputMemberMessage{
+					Value: types.Message{
+						Role:    types.ConversationRoleAssistant,
+						Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "Hello"}},
+					},
+				},
+				StopReason: types.StopReasonEndTurn,
+				Usage:      &types.TokenUsage{InputTokens: aws.Int32(2), OutputTokens: aws.Int32(1), TotalTokens: aws.Int32(3)},
+			},
+		},
+		ModelMap:  bedrock.ModelMap{},
+		Responses: handler.NewResponseStore(10),
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(
+		`{"model": "gpt-4o", "input": "Hi"}`,
+	))
+	w := httptest.NewRecorder()
+	h.HandleResponses(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp convert.ResponseObject
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Object != "response" || resp.Status != "completed" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if len(resp.Output) != 1 || resp.Output[0].Content[0].Text != "Hello" {
+		t.Errorf("Unexpected output: %+v", resp.Output)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 3 {
+		t.Errorf("Expected usage of 3 tokens, got %+v", resp.Usage)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/responses/"+resp.ID, nil)
+	w = httptest.NewRecorder()
+	h.HandleResponses(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), resp.ID) {
+		t.Errorf("Expected stored response, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(
+		`{"model": "gpt-4o", "input": "And again?", "previous_response_id": "`+resp.ID+`"}`,
+	))
+	w = httptest.NewRecorder()
+	h.HandleResponses(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d for a continued response, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(
+		`{"model": "gpt-4o", "input": "Hi", "previous_response_id": "resp_unknown"}`,
+	))
+	w = httptest.NewRecorder()
+	h.HandleResponses(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d for an unknown previous response, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandleStreamedResponses(t *testing.T) {
+	h := handler.Handler{
+		Converser: mockBedrockClient{
+			events: []types.ConverseStreamOutput{
+				&types.ConverseStreamOutputMemberMessageStart{Value: types.MessageStartEvent{Role: types.ConversationRoleAssistant}},
+				&types.ConverseStreamOutputMemberContentBlockDelta{
+					Value: types.ContentBlockDeltaEvent{
+						ContentBlockIndex: aws.Int32(0),
+						Delta:             &types.ContentBlockDeltaMemberText{Value: "Hi"},
+					},
+				},
+				&types.ConverseStreamOutputMemberMessageStop{Value: types.MessageStopEvent{StopReason: types.StopReasonEndTurn}},
+			},
+		},
+		ModelMap:  bedrock.ModelMap{},
+		Responses: handler.NewResponseStore(10),
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(
+		`{"model": "gpt-4o", "input": "Hello", "stream": true}`,
+	))
+	w := httptest.NewRecorder()
+	h.HandleResponses(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var names []string
+	var last convert.ResponseStreamEvent
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			names = append(names, name)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			if err := json.Unmarshal([]byte(data), &last); err != nil {
+				t.Fatalf("Failed to decode event %q: %v", data, err)
+			}
+		}
+	}
+
+	if len(names) != 9 || names[0] != "response.created" || names[len(names)-1] != "response.completed" {
+		t.Fatalf("Unexpected events: %v", names)
+	}
+	if last.Response == nil || last.Response.Output[0].Content[0].Text != "Hi" {
+		t.Errorf("Unexpected final response: %+v", last.Response)
+	}
+	if _, ok := h.Responses.Get(last.Response.ID); !ok {
+		t.Errorf("Expected streamed response %s to be stored", last.Response.ID)
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Server routes requests to the current Handler. Replacing the Handler
+// only affects new requests; those in flight, streams included, finish
+// with the Handler they started with.
+type Server struct {
+	handler atomic.Pointer[Handler]
+}
+
+func NewServer(h Handler) *Server {
+	s := &Server{}
+	s.Store(h)
+	return s
+}
+
+func (s *Server) Store(h Handler) {
+	s.handler.Store(&h)
+}
+
+func (s *Server) Load() Handler {
+	return *s.handler.Load()
+}
+
+// Routes returns the routes of every supported API.
+func (s *Server) Routes() *http.ServeMux {
+	routes := []struct {
+		pattern      string
+		serve        func(Handler, http.ResponseWriter, *http.Request)
+		unauthorized func(http.ResponseWriter)
+	}{
+		{"/v1/chat/completions", Handler.HandleChatCompletions, writeUnauthorized},
+		{"/v1/completions", Handler.HandleCompletions, writeUnauthorized},
+		{"/v1/responses", Handler.HandleResponses, writeUnauthorized},
+		{"/v1/responses/", Handler.HandleResponses, writeUnauthorized},
+		{"/v1/embeddings", Handler.HandleEmbeddings, writeUnauthorized},
+		{"/v1/models", Handler.HandleModels, writeUnauthorized},
+		{"/v1/models/", Handler.HandleModels, writeUnauthorized},
+		{"/openai/deployments/", Handler.HandleAzureDeployments, writeUnauthorized},
+		{"/v1/messages", Handler.HandleAnthropicMessages, func(w http.ResponseWriter) {
+			writeAnthropicError(w, http.StatusUnauthorized, "Invalid API key")
+		}},
+		{"/api/chat", Handler.HandleOllamaChat, writeOllamaUnauthorized},
+		{"/api/generate", Handler.HandleOllamaGenerate, writeOllamaUnauthorized},
+		{"/api/tags", Handler.HandleOllamaTags, writeOllamaUnauthorized},
+		{"/api/show", Handler.HandleOllamaShow, writeOllamaUnauthorized},
+		{"/debug/vars", Handler.HandleMetrics, writeUnauthorized},
+	}
+
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		mux.HandleFunc(route.pattern, func(w http.ResponseWriter, r *http.Request) {
+			h := s.Load()
+			if !h.authorized(r) {
+				route.unauthorized(w)
+				return
+			}
+			route.serve(h, w, r)
+		})
+	}
+	return mux
+}
+
+func writeUnauthorized(w http.ResponseWriter) {
+	writeError(w, http.StatusUnauthorized, errorTypeInvalidRequest, "invalid_api_key", "", "Invalid API key")
+}
+
+func writeOllamaUnauthorized(w http.ResponseWriter) {
+	writeOllamaError(w, http.StatusUnauthorized, "Invalid API key")
+}
+
+// authorized reports whether the request carries one of the API keys, in
+// any of the headers the supported SDKs send it in. Without API keys every
+// request is authorized.
+func (h Handler) authorized(r *http.Request) bool {
+	if len(h.APIKeys) == 0 {
+		return true
+	}
+	keys := []string{r.Header.Get("X-Api-Key"), r.Header.Get("Api-Key")}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		keys = append(keys, token)
+	}
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		for _, apiKey := range h.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/DefangLabs/bedrock-sidecar/handler"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// gatedBedrockClient reports the model of each stream it starts and holds
+// the stream's only event back until gate is closed.
+type gatedBedrockClient struct {
+	mockBedrockClient
+	models chan string
+	gate   chan struct{}
+}
+
+func (m gatedBedrockClient) ConverseStream(
+	_ context.Context,
+	params *bedrockruntime.ConverseStreamInput,
+	_ ...func(*bedrockruntime.Options),
+) (bedrock.EventStream, error) {
+	m.models <- aws.ToString(params.ModelId)
+	events := make(chan types.ConverseStreamOutput, 1)
+	go func() {
+		<-m.gate
+		events <- &types.ConverseStreamOutputMemberContentBlockDelta{
+			Value: types.ContentBlockDeltaEvent{
+				ContentBlockIndex: aws.Int32(0),
+				Delta:             &types.ContentBlockDeltaMemberText{Value: "Hi"},
+			},
+		}
+		close(events)
+	}()
+	return &mockEventStream{events: events}, nil
+}
+
+func TestServerStoreKeepsInFlightStreams(t *testing.T) {
+	client := gatedBedrockClient{models: make(chan string, 2), gate: make(chan struct{})}
+	modelMap := func(modelID string) bedrock.ModelMap {
+		return bedrock.ModelMap{Aliases: map[string]bedrock.ModelConfig{"gpt-4o": {ModelID: modelID}}}
+	}
+	server := handler.NewServer(handler.Handler{Converser: client, ModelMap: modelMap("model-a")})
+	ts := httptest.NewServer(server.Routes())
+	defer ts.Close()
+
+	body := `{"model": "gpt-4o", "stream": true, "messages": [{"role": "user", "content": "Hello"}]}`
+	type result struct {
+		status int
+		body   string
+	}
+	first := make(chan result, 1)
+	go func() {
+		resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+		if err != nil {
+			first <- result{}
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		first <- result{resp.StatusCode, string(data)}
+	}()
+
+	select {
+	case model := <-client.models:
+		if model != "model-a" {
+			t.Fatalf("Expected the first stream to use model-a, got %q", model)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the first stream to start")
+	}
+
+	next := server.Load()
+	next.ModelMap = modelMap("model-b")
+	server.Store(next)
+	close(client.gate)
+
+	select {
+	case res := <-first:
+		if res.status != http.StatusOK || !strings.HasSuffix(res.body, "data: [DONE]\n\n") {
+			t.Fatalf("Expected the in-flight stream to complete, got %d %q", res.status, res.body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the in-flight stream to complete")
+	}
+
+	resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	if model := <-client.models; model != "model-b" {
+		t.Errorf("Expected a new stream to use model-b, got %q", model)
+	}
+}
+
+func TestServerAPIKeys(t *testing.T) {
+	server := handler.NewServer(handler.Handler{
+		ModelMap: bedrock.ModelMap{},
+		APIKeys:  []string{"secret"},
+	})
+	routes := server.Routes()
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		header         string
+		value          string
+		expectedStatus int
+		expectedError  string
+	}{
+		{"missing", http.MethodGet, "/v1/models", "", "", http.StatusUnauthorized, `"code":"invalid_api_key"`},
+		{"wrong", http.MethodGet, "/v1/models", "Authorization", "Bearer nope", http.StatusUnauthorized, `"code":"invalid_api_key"`},
+		{"bearer", http.MethodGet, "/v1/models", "Authorization", "Bearer secret", http.StatusOK, ""},
+		{"azure", http.MethodGet, "/openai/deployments/gpt-4o/chat/completions?api-version=2024-10-21", "api-key", "secret", http.StatusMethodNotAllowed, ""},
+		{"anthropic", http.MethodGet, "/v1/messages", "x-api-key", "secret", http.StatusMethodNotAllowed, ""},
+		{"anthropic missing", http.MethodGet, "/v1/messages", "", "", http.StatusUnauthorized, `"type":"authentication_error"`},
+		{"ollama missing", http.MethodGet, "/api/tags", "", "", http.StatusUnauthorized, `"error":"Invalid API key"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedError != "" {
+				if !json.Valid(w.Body.Bytes()) || !strings.Contains(w.Body.String(), tt.expectedError) {
+					t.Errorf("Expected an error containing %s, got %s", tt.expectedError, w.Body.String())
+				}
+			}
+		})
+	}
+
+	next := server.Load()
+	next.APIKeys = nil
+	server.Store(next)
+	w := httptest.NewRecorder()
+	routes.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d without API keys, got %d", http.StatusOK, w.Code)
+	}
+}
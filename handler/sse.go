@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// sseWriter writes server-sent events, sending the stream headers with the
+// first event so that errors before it can still use a regular response.
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	rc      *http.ResponseController
+	started bool
+	// ndjson streams newline-delimited JSON with writeLine instead, which is
+	// how Ollama streams.
+	ndjson bool
+}
+
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	return &sseWriter{w: w, flusher: flusher, rc: http.NewResponseController(w)}, true
+}
+
+// setDeadline replaces the server's read and write timeouts, which are sized
+// for ordinary requests, with one covering the whole stream. The request
+// body has already been read by the time a stream starts.
+func (s *sseWriter) setDeadline(deadline time.Time) error {
+	if err := s.rc.SetReadDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return fmt.Errorf("%w: failed to clear read deadline", err)
+	}
+	if err := s.rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return fmt.Errorf("%w: failed to set write deadline", err)
+	}
+	return nil
+}
+
+func (s *sseWriter) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	if s.ndjson {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+		return
+	}
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.Header().Set("Connection", "keep-alive")
+}
+
+func (s *sseWriter) write(message string) error {
+	s.start()
+	if _, err := fmt.Fprint(s.w, message); err != nil {
+		return fmt.Errorf("%w: failed to write data", err)
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *sseWriter) writeData(data []byte) error {
+	return s.write(fmt.Sprintf("data: %s\n\n", data))
+}
+
+func (s *sseWriter) writeJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%w: failed to encode response", err)
+	}
+	return s.writeData(data)
+}
+
+// writeLine sends v as one line of newline-delimited JSON.
+func (s *sseWriter) writeLine(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%w: failed to encode response", err)
+	}
+	return s.write(fmt.Sprintf("%s\n", data))
+}
+
+// writeEvent sends a named event, as used by APIs with typed events.
+func (s *sseWriter) writeEvent(event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%w: failed to encode event", err)
+	}
+	return s.write(fmt.Sprintf("event: %s\ndata: %s\n\n", event, data))
+}
+
+// writeComment sends a comment line, which clients ignore but which keeps
+// proxies and load balancers from treating a quiet stream as dead.
+func (s *sseWriter) writeComment(comment string) error {
+	return s.write(fmt.Sprintf(": %s\n\n", comment))
+}
+
+func (s *sseWriter) writeDone() error {
+	return s.writeData([]byte("[DONE]"))
+}
+
+// writeError reports err as an OpenAI error event, which is the only way
+// to signal a failure once the stream headers have been sent.
+func (s *sseWriter) writeError(err error) error {
+	_, errType, code := classifyBedrockError(err)
+	body := OpenAIErrorResponse{
+		Error: OpenAIError{
+			Message: err.Error(),
+			Type:    errType,
+		},
+	}
+	if code != "" {
+		body.Error.Code = &code
+	}
+	return s.writeJSON(body)
+}
@@ -1,26 +1,31 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/DefangLabs/bedrock-sidecar/bedrock"
+	"github.com/DefangLabs/bedrock-sidecar/config"
+	"github.com/DefangLabs/bedrock-sidecar/convert"
 	"github.com/DefangLabs/bedrock-sidecar/handler"
 )
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	configPath := os.Getenv("CONFIG_FILE")
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		slog.Error("Failed to load config", "error", err)
+		os.Exit(1)
 	}
+	setLogLevel(cfg.Logging)
 
-	debug := os.Getenv("DEBUG")
-	if debug != "" {
-		slog.SetLogLoggerLevel(slog.LevelDebug)
-	} else {
-		slog.SetLogLoggerLevel(slog.LevelWarn)
+	if os.Getenv("FETCH_REMOTE_IMAGES") != "" {
+		convert.SetImageFetcher(convert.NewHTTPImageFetcher(10 * time.Second))
 	}
 
 	bedrockController, err := bedrock.NewController()
@@ -29,28 +34,78 @@ func main() {
 		os.Exit(1)
 	}
 
-	modelMap, err := bedrock.NewModelMap()
+	modelMap, err := cfg.Models.ModelMap(bedrockController.Region())
 	if err != nil {
 		slog.Error("Failed to create bedrock.ModelMap", "error", err)
 		os.Exit(1)
 	}
+	if os.Getenv("DISCOVER_INFERENCE_PROFILES") != "" {
+		lister, err := bedrock.NewCatalogLister()
+		if err != nil {
+			slog.Error("Failed to create Bedrock control-plane client", "error", err)
+			os.Exit(1)
+		}
+		if err := modelMap.Profiles.Discover(context.Background(), lister); err != nil {
+			slog.Warn("Failed to discover inference profiles, using built-in list", "error", err)
+		}
+	}
+	logModelMap(modelMap)
+
+	maxChoices := handler.DefaultMaxChoices
+	if value := os.Getenv("MAX_CHOICES"); value != "" {
+		maxChoices, err = strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Failed to parse MAX_CHOICES", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	maxStoredResponses := handler.DefaultMaxStoredResponses
+	if value := os.Getenv("MAX_STORED_RESPONSES"); value != "" {
+		maxStoredResponses, err = strconv.Atoi(value)
+		if err != nil {
+			slog.Error("Failed to parse MAX_STORED_RESPONSES", "error", err)
+			os.Exit(1)
+		}
+	}
 
-	handler := handler.Handler{
-		Converser: bedrockController,
-		ModelMap:  modelMap,
+	var catalog *bedrock.Catalog
+	if os.Getenv("LIST_BEDROCK_MODELS") != "" {
+		catalog, err = bedrock.NewCatalogController(durationEnv("MODEL_CATALOG_TTL", time.Hour))
+		if err != nil {
+			slog.Error("Failed to create bedrock.Catalog", "error", err)
+			os.Exit(1)
+		}
 	}
 
-	http.HandleFunc("/v1/chat/completions", handler.HandleChatCompletions)
-	http.HandleFunc("/api/chat", handler.HandleChatCompletions)
+	server := handler.NewServer(handler.Handler{
+		Converser:         bedrockController,
+		Embedder:          bedrockController,
+		ModelMap:          modelMap,
+		MaxChoices:        maxChoices,
+		MaxStreamDuration: time.Duration(cfg.Listen.StreamMaxDuration),
+		StreamIdleTimeout: time.Duration(cfg.Listen.StreamIdleTimeout),
+		HeartbeatInterval: time.Duration(cfg.Listen.StreamHeartbeatInterval),
+		Catalog:           catalog,
+		Responses:         handler.NewResponseStore(maxStoredResponses),
+		APIKeys:           cfg.Auth.APIKeys,
+	})
+
+	if configPath != "" {
+		go config.Watch(context.Background(), configPath, durationEnv("CONFIG_POLL_INTERVAL", 5*time.Second), func() {
+			reloadConfig(server, configPath, cfg.Listen, bedrockController.Region())
+		})
+	}
 
-	slog.Info("Listening", "port", port)
+	slog.Info("Listening", "port", cfg.Listen.Port)
 
 	srv := &http.Server{
-		Addr:              ":" + port,
-		ReadTimeout:       60 * time.Second,
-		WriteTimeout:      60 * time.Second,
-		IdleTimeout:       60 * time.Second,
-		ReadHeaderTimeout: 2 * time.Second,
+		Addr:              ":" + cfg.Listen.Port,
+		Handler:           server.Routes(),
+		ReadTimeout:       time.Duration(cfg.Listen.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.Listen.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.Listen.IdleTimeout),
+		ReadHeaderTimeout: time.Duration(cfg.Listen.ReadHeaderTimeout),
 	}
 
 	if err := srv.ListenAndServe(); err != nil {
@@ -58,3 +113,74 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// reloadConfig swaps in the model map, API keys, stream settings and log
+// level of the config file, keeping the current ones if it is invalid. The
+// HTTP server is only configured at startup.
+func reloadConfig(server *handler.Server, path string, listen config.Listen, region string) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		slog.Error("Failed to reload config, keeping the current one", "error", err)
+		return
+	}
+	modelMap, err := cfg.Models.ModelMap(region)
+	if err != nil {
+		slog.Error("Failed to reload config, keeping the current one", "error", err)
+		return
+	}
+
+	h := server.Load()
+	modelMap.Profiles.Profiles = h.ModelMap.Profiles.Profiles
+	modelMap.Profiles.OnDemand = h.ModelMap.Profiles.OnDemand
+	h.ModelMap = modelMap
+	h.APIKeys = cfg.Auth.APIKeys
+	h.MaxStreamDuration = time.Duration(cfg.Listen.StreamMaxDuration)
+	h.StreamIdleTimeout = time.Duration(cfg.Listen.StreamIdleTimeout)
+	h.HeartbeatInterval = time.Duration(cfg.Listen.StreamHeartbeatInterval)
+	server.Store(h)
+
+	setLogLevel(cfg.Logging)
+	if cfg.Listen.NeedsRestart(listen) {
+		slog.Warn("Listener settings changed, restart to apply them")
+	}
+	slog.Info("Reloaded config", "path", path)
+	logModelMap(modelMap)
+}
+
+func setLogLevel(logging config.Logging) {
+	level, err := logging.SlogLevel()
+	if err != nil {
+		level = slog.LevelWarn
+	}
+	slog.SetLogLoggerLevel(level)
+}
+
+func logModelMap(modelMap bedrock.ModelMap) {
+	names := modelMap.Names()
+	aliases := make([]string, 0, len(names))
+	for _, name := range names {
+		aliases = append(aliases, name+"="+modelMap.BedrockModelID(name))
+	}
+	slog.Info("Resolved model aliases",
+		"region", modelMap.Profiles.Region,
+		"aliases", strings.Join(aliases, ", "),
+		"rules", len(modelMap.Rules),
+		"strict", modelMap.Strict,
+		"inference_profiles", modelMap.Profiles.Mode,
+	)
+}
+
+// durationEnv parses a Go duration such as "90s" or "5m" from the named
+// environment variable, exiting if it is malformed.
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Error("Failed to parse "+name, "error", err)
+		os.Exit(1)
+	}
+	return duration
+}